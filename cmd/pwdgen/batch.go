@@ -0,0 +1,88 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+)
+
+const _maxCount = 1000000
+
+// generator is a password source of a batch, see Policy and Mask.
+type generator interface {
+	Generate(reader io.Reader) (string, error)
+	Entropy() float64
+	String() string
+}
+
+// batchRecord is one password of a batch, see writeBatch.
+type batchRecord struct {
+	Password string  `json:"password"`
+	Entropy  float64 `json:"entropy"`
+	// EntropyIsBound marks Entropy as an upper bound, see
+	// Policy.EntropyIsBound.
+	EntropyIsBound bool   `json:"entropy_is_upper_bound,omitempty"`
+	Policy         string `json:"policy"`
+}
+
+// writeBatch generates count passwords and streams them to w, format is one
+// of plain (one password per line), json (one object per line) or csv.
+func writeBatch(w io.Writer, reader io.Reader, gen generator, count int, format string) error {
+	if count < 1 || count > _maxCount {
+		return fmt.Errorf("count must range 1-%d", _maxCount)
+	}
+
+	bw := bufio.NewWriter(w)
+	var write func(r batchRecord) error
+	switch format {
+	case "plain":
+		write = func(r batchRecord) error {
+			_, err := fmt.Fprintln(bw, r.Password)
+			return err
+		}
+	case "json":
+		enc := json.NewEncoder(bw)
+		enc.SetEscapeHTML(false)
+		write = func(r batchRecord) error {
+			return enc.Encode(r)
+		}
+	case "csv":
+		cw := csv.NewWriter(bw)
+		if err := cw.Write([]string{"password", "entropy", "policy"}); err != nil {
+			return err
+		}
+		write = func(r batchRecord) error {
+			entropy := strconv.FormatFloat(r.Entropy, 'f', 1, 64)
+			if err := cw.Write([]string{r.Password, entropy, r.Policy}); err != nil {
+				return err
+			}
+			cw.Flush()
+			return cw.Error()
+		}
+	default:
+		return fmt.Errorf("unsupported format %s, want plain, json or csv", format)
+	}
+
+	var (
+		desc    = gen.String()
+		entropy = math.Round(gen.Entropy()*10) / 10
+		bound   = false
+	)
+	if p, ok := gen.(Policy); ok {
+		bound = p.EntropyIsBound()
+	}
+	for i := 0; i < count; i++ {
+		s, err := gen.Generate(reader)
+		if err != nil {
+			return err
+		}
+		if err := write(batchRecord{Password: s, Entropy: entropy, EntropyIsBound: bound, Policy: desc}); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
@@ -0,0 +1,318 @@
+package main
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"software.sslmate.com/src/go-pkcs12"
+)
+
+// certKeyTypes lists the key types supported by cert.
+var certKeyTypes = []string{"ecdsa", "ed25519", "rsa"}
+
+func generateCertKey(reader io.Reader, keyType string, bits int) (crypto.Signer, error) {
+	switch keyType {
+	case "ecdsa":
+		return ecdsa.GenerateKey(elliptic.P256(), reader)
+	case "ed25519":
+		_, k, err := ed25519.GenerateKey(reader)
+		return k, err
+	case "rsa":
+		if bits < 2048 || bits > 16384 {
+			return nil, errors.New("rsa bits must range 2048-16384")
+		}
+		return rsa.GenerateKey(reader, bits)
+	default:
+		return nil, fmt.Errorf("unsupported key type %s, want one of %s", keyType, strings.Join(certKeyTypes, ", "))
+	}
+}
+
+func randomSerial(reader io.Reader) (*big.Int, error) {
+	return rand.Int(reader, new(big.Int).Lsh(big.NewInt(1), 128))
+}
+
+// parseSANs splits the names into IP addresses and DNS names.
+func parseSANs(names []string) ([]string, []net.IP) {
+	var (
+		dns []string
+		ips []net.IP
+	)
+	for _, name := range names {
+		if ip := net.ParseIP(name); ip != nil {
+			ips = append(ips, ip)
+		} else {
+			dns = append(dns, name)
+		}
+	}
+	return dns, ips
+}
+
+// newCA creates a self-signed root CA that can only sign leaf certificates.
+func newCA(reader io.Reader, cn string, validity time.Duration, key crypto.Signer) (*x509.Certificate, error) {
+	serial, err := randomSerial(reader)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	tmpl := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: cn, Organization: []string{"pwdgen"}},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(validity),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		MaxPathLenZero:        true,
+	}
+	der, err := x509.CreateCertificate(reader, tmpl, tmpl, key.Public(), key)
+	if err != nil {
+		return nil, err
+	}
+	return x509.ParseCertificate(der)
+}
+
+// newLeaf issues a TLS certificate for the names, it is self-signed when ca
+// is nil.
+func newLeaf(reader io.Reader, names []string, validity time.Duration, key crypto.Signer, client bool, ca *x509.Certificate, caKey crypto.Signer) (*x509.Certificate, error) {
+	if len(names) == 0 {
+		return nil, errors.New("at least one dns name or ip is required")
+	}
+	serial, err := randomSerial(reader)
+	if err != nil {
+		return nil, err
+	}
+	dns, ips := parseSANs(names)
+	now := time.Now()
+	tmpl := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: names[0], Organization: []string{"pwdgen"}},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(validity),
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		DNSNames:              dns,
+		IPAddresses:           ips,
+	}
+	if _, ok := key.(*rsa.PrivateKey); ok {
+		tmpl.KeyUsage |= x509.KeyUsageKeyEncipherment
+	}
+	if client {
+		tmpl.ExtKeyUsage = append(tmpl.ExtKeyUsage, x509.ExtKeyUsageClientAuth)
+	}
+	parent, signer := tmpl, key
+	if ca != nil {
+		parent, signer = ca, caKey
+	}
+	der, err := x509.CreateCertificate(reader, tmpl, parent, key.Public(), signer)
+	if err != nil {
+		return nil, err
+	}
+	return x509.ParseCertificate(der)
+}
+
+func encodeCertPEM(cert *x509.Certificate) []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})
+}
+
+func encodeKeyPEM(key crypto.Signer) ([]byte, error) {
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), nil
+}
+
+// loadCA loads the CA written by cert ca, name is the prefix of the files.
+func loadCA(name string) (*x509.Certificate, crypto.Signer, error) {
+	certPEM, err := os.ReadFile(name + ".pem")
+	if err != nil {
+		return nil, nil, err
+	}
+	keyPEM, err := os.ReadFile(name + "-key.pem")
+	if err != nil {
+		return nil, nil, err
+	}
+	block, _ := pem.Decode(certPEM)
+	if block == nil || block.Type != "CERTIFICATE" {
+		return nil, nil, fmt.Errorf("%s.pem: no certificate found", name)
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !cert.IsCA {
+		return nil, nil, fmt.Errorf("%s.pem is not a ca certificate", name)
+	}
+	block, _ = pem.Decode(keyPEM)
+	if block == nil {
+		return nil, nil, fmt.Errorf("%s-key.pem: no private key found", name)
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, nil, err
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, nil, fmt.Errorf("%s-key.pem: unsupported private key", name)
+	}
+	return cert, signer, nil
+}
+
+func certFingerprint(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.Raw)
+	return strings.ToUpper(hex.EncodeToString(sum[:]))
+}
+
+func (m *PwdGenCLI) CertCA(cmd *cobra.Command, args []string) error {
+	out, _ := cmd.Flags().GetString("out")
+	cn, _ := cmd.Flags().GetString("cn")
+	days, _ := cmd.Flags().GetInt("days")
+	keyType, _ := cmd.Flags().GetString("key-type")
+	bits, _ := cmd.Flags().GetInt("bits")
+	force, _ := cmd.Flags().GetBool("force")
+
+	if days < 1 {
+		return errors.New("days must >= 1")
+	}
+	if !force {
+		if err := keyFilesFree(out+"-key.pem", out+".pem"); err != nil {
+			return err
+		}
+	}
+	key, err := generateCertKey(rand.Reader, keyType, bits)
+	if err != nil {
+		return err
+	}
+	ca, err := newCA(rand.Reader, cn, time.Duration(days)*24*time.Hour, key)
+	if err != nil {
+		return fmt.Errorf("create ca err: %w", err)
+	}
+	keyPEM, err := encodeKeyPEM(key)
+	if err != nil {
+		return err
+	}
+	if err := writeKeyFile(out+"-key.pem", keyPEM, 0o600, force); err != nil {
+		return err
+	}
+	if err := writeKeyFile(out+".pem", encodeCertPEM(ca), 0o644, force); err != nil {
+		os.Remove(out + "-key.pem")
+		return err
+	}
+	fmt.Printf("CA 证书: %s.pem\nCA 私钥: %s-key.pem\nSHA-256 指纹: %s\n有效期至: %s\n",
+		out, out, certFingerprint(ca), ca.NotAfter.Local().Format(time.DateTime))
+	fmt.Fprintf(os.Stderr, "把 %s.pem 加入系统或浏览器的受信任根证书后, 由它签发的证书即被信任\n", out)
+	return nil
+}
+
+func (m *PwdGenCLI) CertIssue(cmd *cobra.Command, args []string) error {
+	caName, _ := cmd.Flags().GetString("ca")
+	selfSigned, _ := cmd.Flags().GetBool("self-signed")
+	out, _ := cmd.Flags().GetString("out")
+	days, _ := cmd.Flags().GetInt("days")
+	keyType, _ := cmd.Flags().GetString("key-type")
+	bits, _ := cmd.Flags().GetInt("bits")
+	client, _ := cmd.Flags().GetBool("client")
+	format, _ := cmd.Flags().GetString("format")
+	legacy, _ := cmd.Flags().GetBool("legacy")
+	force, _ := cmd.Flags().GetBool("force")
+
+	if days < 1 {
+		return errors.New("days must >= 1")
+	}
+	if format != "pem" && format != "p12" {
+		return fmt.Errorf("unsupported format %s, want pem or p12", format)
+	}
+	if out == "" {
+		out = strings.NewReplacer("*", "_wildcard", ":", "_").Replace(args[0])
+	}
+	if !force {
+		paths := []string{out + "-key.pem", out + ".pem"}
+		if format == "p12" {
+			paths = []string{out + ".p12"}
+		}
+		if err := keyFilesFree(paths...); err != nil {
+			return err
+		}
+	}
+
+	var (
+		ca    *x509.Certificate
+		caKey crypto.Signer
+		err   error
+	)
+	if !selfSigned {
+		if ca, caKey, err = loadCA(caName); err != nil {
+			return fmt.Errorf("load ca err: %w, create one with pwdgen cert ca or use --self-signed", err)
+		}
+	}
+	key, err := generateCertKey(rand.Reader, keyType, bits)
+	if err != nil {
+		return err
+	}
+	leaf, err := newLeaf(rand.Reader, args, time.Duration(days)*24*time.Hour, key, client, ca, caKey)
+	if err != nil {
+		return fmt.Errorf("issue certificate err: %w", err)
+	}
+
+	fmt.Printf("SAN: %s\nSHA-256 指纹: %s\n有效期至: %s\n",
+		strings.Join(args, ", "), certFingerprint(leaf), leaf.NotAfter.Local().Format(time.DateTime))
+
+	if format == "pem" {
+		keyPEM, err := encodeKeyPEM(key)
+		if err != nil {
+			return err
+		}
+		if err := writeKeyFile(out+"-key.pem", keyPEM, 0o600, force); err != nil {
+			return err
+		}
+		if err := writeKeyFile(out+".pem", encodeCertPEM(leaf), 0o644, force); err != nil {
+			os.Remove(out + "-key.pem")
+			return err
+		}
+		fmt.Printf("证书: %s.pem\n私钥: %s-key.pem\n", out, out)
+		return nil
+	}
+
+	// Letters and digits only, some PKCS#12 importers choke on symbols.
+	password, err := setLevel(3, 20).Policy().Generate(rand.Reader)
+	if err != nil {
+		return err
+	}
+	var chain []*x509.Certificate
+	if ca != nil {
+		chain = append(chain, ca)
+	}
+	encoder := pkcs12.Modern2023
+	if legacy {
+		encoder = pkcs12.LegacyDES
+	}
+	data, err := encoder.Encode(key, leaf, chain, password)
+	if err != nil {
+		return fmt.Errorf("encode pkcs12 err: %w", err)
+	}
+	if err := writeKeyFile(out+".p12", data, 0o600, force); err != nil {
+		return err
+	}
+	fmt.Printf("PKCS#12: %s.p12\n", out)
+	fmt.Fprintln(os.Stderr, "PKCS#12 密码:")
+	return writeOutput(cmd, password)
+}
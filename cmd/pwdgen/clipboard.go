@@ -0,0 +1,88 @@
+package main
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/atotto/clipboard"
+	"github.com/spf13/cobra"
+)
+
+// _clipClearCmd is the hidden subcommand run by the detached helper.
+const _clipClearCmd = "clipclear"
+
+// copySecret writes the secret to the clipboard. When clearAfter > 0 a
+// detached helper clears the clipboard after that time, but only if it still
+// holds the secret.
+func copySecret(s string, clearAfter time.Duration) error {
+	if err := clipboard.WriteAll(s); err != nil {
+		return err
+	}
+	if clearAfter <= 0 {
+		return nil
+	}
+	if err := startClipClear(s, clearAfter); err != nil {
+		return fmt.Errorf("start clipboard clear helper err: %w", err)
+	}
+	fmt.Fprintf(os.Stderr, "已复制到剪贴板, %s 后自动清除\n", clearAfter)
+	return nil
+}
+
+// startClipClear starts the helper, only the SHA-256 of the secret is passed
+// to it through stdin.
+func startClipClear(s string, after time.Duration) error {
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	c := exec.Command(exe, _clipClearCmd, "--after", after.String())
+	detach(c)
+	stdin, err := c.StdinPipe()
+	if err != nil {
+		return err
+	}
+	if err := c.Start(); err != nil {
+		return err
+	}
+	sum := sha256.Sum256([]byte(s))
+	if _, err := io.WriteString(stdin, hex.EncodeToString(sum[:])); err != nil {
+		return err
+	}
+	if err := stdin.Close(); err != nil {
+		return err
+	}
+	return c.Process.Release()
+}
+
+func (m *PwdGenCLI) ClipClear(cmd *cobra.Command, args []string) error {
+	after, _ := cmd.Flags().GetDuration("after")
+
+	b, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		return err
+	}
+	want, err := hex.DecodeString(strings.TrimSpace(string(b)))
+	if err != nil {
+		return err
+	}
+
+	time.Sleep(after)
+
+	current, err := clipboard.ReadAll()
+	if err != nil {
+		return err
+	}
+	sum := sha256.Sum256([]byte(current))
+	if subtle.ConstantTimeCompare(sum[:], want) != 1 {
+		// The clipboard has been changed by the user, leave it alone.
+		return nil
+	}
+	return clipboard.WriteAll("")
+}
@@ -0,0 +1,19 @@
+//go:build windows
+
+package main
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// _detachedProcess is DETACHED_PROCESS, the process has no console.
+const _detachedProcess = 0x00000008
+
+// detach starts the command without console, so it survives the terminal.
+func detach(c *exec.Cmd) {
+	c.SysProcAttr = &syscall.SysProcAttr{
+		CreationFlags: _detachedProcess | syscall.CREATE_NEW_PROCESS_GROUP,
+		HideWindow:    true,
+	}
+}
@@ -2,6 +2,9 @@ package main
 
 import (
 	"archive/zip"
+	"bufio"
+	"crypto/rand"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -15,8 +18,10 @@ import (
 	"os/exec"
 	"path/filepath"
 	"runtime"
+	"slices"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/atotto/clipboard"
@@ -35,23 +40,159 @@ type PwdGenCLI struct {
 }
 
 func (m *PwdGenCLI) Root(cmd *cobra.Command, args []string) {
-	length, _ := cmd.Flags().GetInt("length")
-	level, _ := cmd.Flags().GetInt("level")
-	output, _ := cmd.Flags().GetInt("output")
-	s, err := fullPassword(level, length)
+	var gen generator
+	if mask, _ := cmd.Flags().GetString("mask"); mask != "" {
+		// The mask fixes length and classes, only the symbol set and the
+		// exclusions of the policy flags apply.
+		for _, name := range maskConflictFlags() {
+			if cmd.Flags().Changed(name) {
+				log.Fatalf("mask err: --%s can not be used with --mask", name)
+			}
+		}
+		var p Policy
+		p.Symbols.Chars, _ = cmd.Flags().GetString("symbols")
+		p.Exclude, _ = cmd.Flags().GetString("exclude")
+		p.NoAmbiguous, _ = cmd.Flags().GetBool("no-ambiguous")
+		m, err := parseMask(mask, p)
+		if err != nil {
+			log.Fatalf("mask err: %v", err)
+		}
+		gen = m
+	} else {
+		policy, err := policyFromFlags(cmd)
+		if err != nil {
+			log.Fatalf("full password err: %v", err)
+		}
+		gen = policy
+	}
+
+	count, _ := cmd.Flags().GetInt("count")
+	format, _ := cmd.Flags().GetString("format")
+	if count > 1 || cmd.Flags().Changed("format") {
+		// Batches are large, buffer the random bytes instead of one syscall per character.
+		reader := bufio.NewReaderSize(rand.Reader, 4096)
+		if err := writeBatch(os.Stdout, reader, gen, count, format); err != nil {
+			log.Fatalf("batch password err: %v", err)
+		}
+		return
+	}
+	s, err := gen.Generate(rand.Reader)
 	if err != nil {
 		log.Fatalf("full password err: %v", err)
 	}
+	if err := writeOutput(cmd, s); err != nil {
+		log.Fatalf("err: %v\n", err)
+	}
+	printStrength(os.Stderr, s)
+	if mask, ok := gen.(Mask); ok {
+		// The estimate above only sees the password, the mask tells the real search space.
+		fmt.Fprintf(os.Stderr, "模板熵: %.1f bits\n", mask.Entropy())
+	}
+}
+
+// addOutputFlags 添加输出方式相关的参数, 见 writeOutput。
+func addOutputFlags(cmd *cobra.Command, output int) {
+	cmd.Flags().IntP("output", "o", output, "输出方式, 1: 剪贴板, 2: 控制台")
+	cmd.Flags().String("qr", "", "同时输出二维码, term: 在终端显示, 其他值: 保存为 png 文件的路径")
+	cmd.Flags().Int("clear-after", 30, "复制到剪贴板后, 经过多少秒自动清除剪贴板")
+	cmd.Flags().Bool("no-clear", false, "不自动清除剪贴板")
+}
+
+// writeOutput 按 output 参数输出生成的密码, 1: 剪贴板, 2: 控制台。
+// 命令有 qr 参数且不为空时, 同时输出二维码。
+func writeOutput(cmd *cobra.Command, s string) error {
+	output, _ := cmd.Flags().GetInt("output")
 	switch output {
 	case 1:
-		if err := clipboard.WriteAll(s); err != nil {
-			log.Fatalf("err: %v\n", err)
+		clearAfter, _ := cmd.Flags().GetInt("clear-after")
+		if noClear, _ := cmd.Flags().GetBool("no-clear"); noClear {
+			clearAfter = 0
+		}
+		if err := copySecret(s, time.Duration(clearAfter)*time.Second); err != nil {
+			return err
 		}
 	case 2:
 		fmt.Println(s)
 	default:
-		log.Fatalf("output param err: not support %d\n", output)
+		return fmt.Errorf("output param err: not support %d", output)
+	}
+
+	if qrTarget, _ := cmd.Flags().GetString("qr"); qrTarget != "" {
+		return writeQR(qrTarget, s)
+	}
+	return nil
+}
+
+var policyClasses = []string{"lower", "upper", "digits", "symbols"}
+
+// addPolicyFlags 添加密码长度、强度等级和字符类相关的参数, 见 policyFromFlags。
+func addPolicyFlags(cmd *cobra.Command) {
+	cmd.Flags().IntP("length", "n", 16, "生成的密码长度, [6, 2048]")
+	cmd.Flags().IntP("level", "l", 4, "生成的密码强度等级, 数字越大, 强度越高, [1, 4]")
+	for _, name := range policyClasses {
+		cmd.Flags().Int("min-"+name, 0, fmt.Sprintf("%s 类字符的最少个数", name))
+		cmd.Flags().Int("max-"+name, -1, fmt.Sprintf("%s 类字符的最多个数, -1: 不限制, 0: 不使用", name))
+	}
+	cmd.Flags().String("symbols", Symbols, "自定义符号集")
+	cmd.Flags().String("exclude", "", "排除的字符")
+	cmd.Flags().Bool("no-ambiguous", false, "排除易混淆的字符, 如 0/O, l/1/I")
+}
+
+// maskConflictFlags 返回与 --mask 冲突的策略参数。
+func maskConflictFlags() []string {
+	names := []string{"length", "level"}
+	for _, name := range policyClasses {
+		names = append(names, "min-"+name, "max-"+name)
 	}
+	return names
+}
+
+// policyFromFlags 根据 level 和字符类相关的参数构造密码策略。
+// 未指定任何 --min-*/--max-* 参数时, 与 level 的固定比例保持一致。
+func policyFromFlags(cmd *cobra.Command) (Policy, error) {
+	length, _ := cmd.Flags().GetInt("length")
+	level, _ := cmd.Flags().GetInt("level")
+	if level < 1 || level > 4 {
+		return Policy{}, errors.New("level must range 1-4")
+	}
+	policy := setLevel(level, length).Policy()
+
+	custom := false
+	for _, name := range policyClasses {
+		if cmd.Flags().Changed("min-"+name) || cmd.Flags().Changed("max-"+name) {
+			custom = true
+		}
+	}
+	if custom {
+		policy = newPolicy(level, length)
+		rules := map[string]*ClassRule{
+			"lower":   &policy.Lower,
+			"upper":   &policy.Upper,
+			"digits":  &policy.Digits,
+			"symbols": &policy.Symbols,
+		}
+		for name, rule := range rules {
+			if cmd.Flags().Changed("min-" + name) {
+				rule.Min, _ = cmd.Flags().GetInt("min-" + name)
+				if rule.Max == 0 {
+					rule.Max = -1
+				}
+			}
+			if cmd.Flags().Changed("max-" + name) {
+				rule.Max, _ = cmd.Flags().GetInt("max-" + name)
+				if !cmd.Flags().Changed("min-"+name) && rule.Max >= 0 {
+					rule.Min = min(rule.Min, rule.Max)
+				}
+			}
+		}
+	}
+
+	if cmd.Flags().Changed("symbols") {
+		policy.Symbols.Chars, _ = cmd.Flags().GetString("symbols")
+	}
+	policy.Exclude, _ = cmd.Flags().GetString("exclude")
+	policy.NoAmbiguous, _ = cmd.Flags().GetBool("no-ambiguous")
+	return policy, policy.Validate()
 }
 
 func (m *PwdGenCLI) Csv2Xykey(cmd *cobra.Command, args []string) error {
@@ -117,32 +258,63 @@ func (m *PwdGenCLI) EncryptFile(cmd *cobra.Command, args []string) error {
 	m.Logger.Debug("encrypt cmd", "args", args)
 
 	genKey, _ := cmd.Flags().GetBool("genkey")
-	key, _ := cmd.Flags().GetString("key")
+	usePassphrase, _ := cmd.Flags().GetBool("passphrase")
 	file, _ := cmd.Flags().GetString("file")
 	text, _ := cmd.Flags().GetString("text")
 	outputDir, _ := cmd.Flags().GetString("output-dir")
+	removeSrc, _ := cmd.Flags().GetBool("remove-source")
+	shredPasses, _ := cmd.Flags().GetInt("shred")
+	force, _ := cmd.Flags().GetBool("force")
 	begin := time.Now()
 
+	if shredPasses > 0 && !removeSrc {
+		return errors.New("--shred requires --remove-source")
+	}
+
 	if genKey {
 		randomHexString, err := rice.RandomHexString(32)
-		fmt.Printf("生成的 AES key :\n\n%s\n\n妥善保存此密钥，此后的加密和解密都需要此密钥。\n", randomHexString)
-		return err
+		if err != nil {
+			return err
+		}
+		fmt.Printf("生成的 AES key :\n\n%s\n\n", randomHexString)
+		if b, err := hex.DecodeString(randomHexString); err == nil {
+			if mnemonic, err := keyToMnemonic(b); err == nil {
+				fmt.Printf("助记词 (可代替 key 使用) :\n\n%s\n\n", mnemonic)
+			}
+		}
+		fmt.Printf("妥善保存此密钥，此后的加密和解密都需要此密钥。\n可使用 pwdgen shamir split 把密钥拆分给多人保管。\n")
+		return nil
 	}
 
-	if key == "" {
-		k, ok := os.LookupEnv("MEI_AES_KEY")
-		if !ok {
-			return fmt.Errorf("env var MEI_AES_KEY not set")
-		}
-		key = k
+	var (
+		kdf    byte = _kdfKey
+		secret string
+		err    error
+	)
+	if usePassphrase {
+		kdf = _kdfArgon2id
+		secret, err = readNewSecret("密码: ", false)
+	} else if secret, err = aesKeyFromFlags(cmd); err == nil {
+		_, err = parseAESKey(secret)
+	}
+	if err != nil {
+		return err
 	}
 
 	if cmd.Flags().Changed("text") && text != "" {
-		encryptText, err := rice.AESGCMEncryptText(key, text)
+		var encryptText string
+		if usePassphrase {
+			encryptText, err = encryptTextWithPassphrase(text, secret)
+		} else {
+			encryptText, err = rice.AESGCMEncryptText(secret, text)
+		}
 		if err != nil {
 			return fmt.Errorf("encrypt text err: %w", err)
 		}
 		fmt.Println(encryptText)
+		if qrTarget, _ := cmd.Flags().GetString("qr"); qrTarget != "" {
+			return writeQR(qrTarget, encryptText)
+		}
 		return nil
 	}
 
@@ -160,54 +332,84 @@ func (m *PwdGenCLI) EncryptFile(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("parse output-dir err: %w", err)
 	}
 
-	if rice.PathIsDir(file) {
+	verifySecret := func(byte) (string, error) { return secret, nil }
 
-		zipFilename := filepath.Join(absOutputDir, filepath.Base(file)+".zip")
-		encryptOutput := zipFilename + Aes256Suffix
+	if rice.PathIsDir(file) {
 
-		if err := ZipFolder(file, zipFilename); err != nil {
-			m.Logger.Error("zip folder err", "err", err)
+		absFile, err := filepath.Abs(file)
+		if err != nil {
+			return err
+		}
+		if rel, err := filepath.Rel(absFile, absOutputDir); err == nil && !strings.HasPrefix(rel, "..") {
+			return fmt.Errorf("输出目录不能位于要加密的文件夹中, output-dir: %s", outputDir)
+		}
+		encryptOutput := filepath.Join(absOutputDir, filepath.Base(absFile)+".zip"+Aes256Suffix)
+		if err := checkOverwrite(encryptOutput, force); err != nil {
 			return err
 		}
 
-		m.Logger.Info("zip folder success", "file", file, "cost", time.Since(begin), "zip_filename", zipFilename)
+		ignoreFiles, _ := cmd.Flags().GetStringArray("ignore-file")
+		patterns, _ := cmd.Flags().GetStringArray("ignore")
+		ignore, err := newIgnoreMatcher(file, ignoreFiles, patterns)
+		if err != nil {
+			return fmt.Errorf("load ignore rules err: %w", err)
+		}
 
-		if err := rice.AESGCMEncryptFile(key, zipFilename, encryptOutput); err != nil {
+		// ZIP 直接写入加密流, 明文不落盘
+		err = encryptContainer(kdf, secret, encryptOutput, func(w io.Writer) error {
+			return ZipFolder(file, w, ignore)
+		})
+		if err != nil {
+			m.Logger.Error("encrypt folder err", "err", err)
 			return err
 		}
-		if err := os.Remove(zipFilename); err != nil {
+		m.Logger.Info("encrypt folder success", "file", file, "cost", time.Since(begin), "output", encryptOutput)
+
+		if !removeSrc {
+			return nil
+		}
+		// 解密并逐个比对文件的 SHA-256, 全部一致才删除源文件
+		files, err := verifyContainerZip(encryptOutput, file, verifySecret)
+		if err != nil {
+			return fmt.Errorf("verify %s err: %w, source kept", encryptOutput, err)
+		}
+		if err := removeFolderFiles(file, files, shredPasses); err != nil {
 			return err
 		}
-		m.Logger.Info("encrypt folder success", "cost", time.Since(begin), "output", encryptOutput)
+		m.Logger.Info("source removed", "file", file, "files", len(files))
 
 	} else {
 		encryptOutput := filepath.Join(absOutputDir, filepath.Base(file)+Aes256Suffix)
-		if err := rice.AESGCMEncryptFile(key, file, encryptOutput); err != nil {
+		if err := checkOverwrite(encryptOutput, force); err != nil {
+			return err
+		}
+		if err := encryptContainerFile(kdf, secret, file, encryptOutput); err != nil {
 			return err
 		}
-		err := os.Remove(file)
 		m.Logger.Info("encrypt file success", "cost", time.Since(begin), "output", encryptOutput)
-		return err
+
+		if !removeSrc {
+			return nil
+		}
+		if err := verifyContainerFile(encryptOutput, file, verifySecret); err != nil {
+			return fmt.Errorf("verify %s err: %w, source kept", encryptOutput, err)
+		}
+		if err := removeSource(file, shredPasses); err != nil {
+			return err
+		}
+		m.Logger.Info("source removed", "file", file)
 	}
 
 	return nil
 }
 
-// ZipFolder 压缩文件夹
-func ZipFolder(sourceDir, zipFile string) error {
-	// 创建目标 ZIP 文件
-	zipFileWriter, err := os.Create(zipFile)
-	if err != nil {
-		return err
-	}
-	defer zipFileWriter.Close()
-
+// ZipFolder 压缩文件夹, 跳过 ignore 匹配的文件和文件夹
+func ZipFolder(sourceDir string, w io.Writer, ignore *ignoreMatcher) error {
 	// 创建 ZIP Writer
-	zipWriter := zip.NewWriter(zipFileWriter)
-	defer zipWriter.Close()
+	zipWriter := zip.NewWriter(w)
 
 	// 遍历目录并添加文件到 ZIP
-	err = filepath.Walk(sourceDir, func(path string, info os.FileInfo, err error) error {
+	err := filepath.Walk(sourceDir, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
 		}
@@ -218,6 +420,13 @@ func ZipFolder(sourceDir, zipFile string) error {
 			return err
 		}
 
+		if relPath != "." && ignore.Match(filepath.ToSlash(relPath), info.IsDir()) {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
 		// 忽略目录，直接处理文件
 		if info.IsDir() {
 			if relPath == "." {
@@ -254,27 +463,40 @@ func ZipFolder(sourceDir, zipFile string) error {
 		_, err = io.Copy(writer, file)
 		return err
 	})
+	if err != nil {
+		return err
+	}
 
-	return err
+	// 写入 ZIP 目录
+	return zipWriter.Close()
 }
 
-func UnzipFolder(src, dest string) error {
-	// 打开 ZIP 文件
-	r, err := zip.OpenReader(src)
+// UnzipFolder 解压 ZIP 到 dest, 除非 force, 已存在的文件不会被覆盖
+func UnzipFolder(src io.ReaderAt, size int64, dest string, force bool) error {
+	r, err := zip.NewReader(src, size)
 	if err != nil {
 		return err
 	}
-	defer r.Close()
 
-	// 遍历 ZIP 文件中的每个条目
+	// 先检查所有目标路径, 有文件已存在时一个都不写
+	var existing []string
 	for _, file := range r.File {
-		// 构造目标文件路径
 		filePath := filepath.Join(dest, file.Name)
-
-		// 检查目标路径是否安全
 		if !strings.HasPrefix(filePath, filepath.Clean(dest)+string(os.PathSeparator)) {
 			return fmt.Errorf("非法文件路径: %s", filePath)
 		}
+		if !file.FileInfo().IsDir() && rice.PathExists(filePath) {
+			existing = append(existing, filePath)
+		}
+	}
+	if len(existing) > 0 && !force {
+		return fmt.Errorf("%d files already exist, use --force to overwrite: %s", len(existing), strings.Join(existing, ", "))
+	}
+
+	// 遍历 ZIP 文件中的每个条目
+	for _, file := range r.File {
+		// 构造目标文件路径
+		filePath := filepath.Join(dest, file.Name)
 
 		// 如果是目录，则创建
 		if file.FileInfo().IsDir() {
@@ -296,17 +518,13 @@ func UnzipFolder(src, dest string) error {
 		if err != nil {
 			return err
 		}
-		defer srcFile.Close()
 
-		// 创建目标文件
-		destFile, err := os.OpenFile(filePath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, file.Mode())
-		if err != nil {
+		// 先写入临时文件再重命名为目标文件
+		err = writeFileAtomic(filePath, file.Mode().Perm(), func(w io.Writer) error {
+			_, err := io.Copy(w, srcFile)
 			return err
-		}
-		defer destFile.Close()
-
-		// 将内容写入目标文件
-		_, err = io.Copy(destFile, srcFile)
+		})
+		srcFile.Close()
 		if err != nil {
 			return err
 		}
@@ -315,21 +533,65 @@ func UnzipFolder(src, dest string) error {
 	return nil
 }
 
+func unzipFile(src, dest string, force bool) error {
+	f, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	return UnzipFolder(f, info.Size(), dest, force)
+}
+
+func verifyZipFile(src, dir string) error {
+	f, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	_, err = verifyZip(f, info.Size(), dir)
+	return err
+}
+
 func (m *PwdGenCLI) DecryptFile(cmd *cobra.Command, args []string) error {
-	key, _ := cmd.Flags().GetString("key")
 	file, _ := cmd.Flags().GetString("file")
 	text, _ := cmd.Flags().GetString("text")
+	outputDir, _ := cmd.Flags().GetString("output-dir")
+	workers, _ := cmd.Flags().GetInt("workers")
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+	force, _ := cmd.Flags().GetBool("force")
+	removeSrc, _ := cmd.Flags().GetBool("remove-source")
+	shredPasses, _ := cmd.Flags().GetInt("shred")
 	begin := time.Now()
 
-	if key == "" {
-		k, ok := os.LookupEnv("MEI_AES_KEY")
-		if !ok {
-			return fmt.Errorf("env var MEI_AES_KEY not set")
-		}
-		key = k
+	if shredPasses > 0 && !removeSrc {
+		return errors.New("--shred requires --remove-source")
 	}
 
 	if text != "" {
+		if data, ok := decodeContainerText(text); ok {
+			secret, err := readSecret("密码: ", false)
+			if err != nil {
+				return err
+			}
+			plaintext, err := decryptTextWithPassphrase(data, secret)
+			if err != nil {
+				return fmt.Errorf("decrypt text err: %w", err)
+			}
+			fmt.Println(plaintext)
+			return nil
+		}
+		key, err := aesKeyFromFlags(cmd)
+		if err != nil {
+			return err
+		}
 		decryptText, err := rice.AESGCMDecryptText(key, text)
 		if err != nil {
 			return fmt.Errorf("decrypt text err: %w", err)
@@ -341,37 +603,232 @@ func (m *PwdGenCLI) DecryptFile(cmd *cobra.Command, args []string) error {
 	if !rice.PathExists(file) {
 		return fmt.Errorf("文件或文件夹不存在, file: %s", file)
 	}
-
-	if !strings.HasSuffix(file, Aes256Suffix) {
-		return fmt.Errorf("文件不是加密文件, file: %s", file)
+	if outputDir != "" && !dryRun {
+		if err := os.MkdirAll(outputDir, 0o755); err != nil {
+			return err
+		}
 	}
 
+	var jobs []decryptJob
 	if rice.PathIsDir(file) {
-		m.Logger.Info("这是一个文件夹，不支持解密")
-
-	} else {
-		outputFile := strings.TrimSuffix(file, Aes256Suffix)
-		if err := rice.AESGCMDecryptFile(key, file, outputFile); err != nil {
+		// 递归解密文件夹中所有的 .aes256 文件, 输出时保留目录结构
+		err := filepath.WalkDir(file, func(path string, d os.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() || !d.Type().IsRegular() || !strings.HasSuffix(path, Aes256Suffix) {
+				return nil
+			}
+			dir := filepath.Dir(path)
+			if outputDir != "" {
+				rel, err := filepath.Rel(file, dir)
+				if err != nil {
+					return err
+				}
+				dir = filepath.Join(outputDir, rel)
+			}
+			jobs = append(jobs, decryptJob{src: path, dir: dir, force: force})
+			return nil
+		})
+		if err != nil {
 			return err
 		}
+		if len(jobs) == 0 {
+			return fmt.Errorf("文件夹中没有加密文件, file: %s", file)
+		}
+	} else {
+		if !strings.HasSuffix(file, Aes256Suffix) {
+			return fmt.Errorf("文件不是加密文件, file: %s", file)
+		}
+		dir := filepath.Dir(file)
+		if outputDir != "" {
+			dir = outputDir
+		}
+		jobs = append(jobs, decryptJob{src: file, dir: dir, force: force})
+	}
 
-		if strings.HasSuffix(outputFile, ".zip") {
+	if dryRun {
+		for _, job := range jobs {
+			exists := ""
+			if rice.PathExists(job.output()) {
+				exists = " (已存在)"
+			}
+			fmt.Printf("%s -> %s%s\n", job.src, job.output(), exists)
+		}
+		fmt.Printf("共 %d 个文件\n", len(jobs))
+		return nil
+	}
 
-			if err := UnzipFolder(outputFile, strings.TrimSuffix(outputFile, ".zip")); err != nil {
-				return err
+	if workers < 1 {
+		workers = 1
+	}
+	var (
+		secrets = &secretCache{cmd: cmd, values: make(map[byte]string)}
+		ch      = make(chan decryptJob)
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		failed  []string
+	)
+	for range min(workers, len(jobs)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range ch {
+				start := time.Now()
+				if err := job.run(secrets.get, removeSrc, shredPasses); err != nil {
+					m.Logger.Error("解密失败", "file", job.src, "err", err)
+					mu.Lock()
+					failed = append(failed, job.src)
+					mu.Unlock()
+					continue
+				}
+				m.Logger.Info("解密成功", "file", job.src, "output", job.output(), "耗时", time.Since(start))
+			}
+		}()
+	}
+	for _, job := range jobs {
+		ch <- job
+	}
+	close(ch)
+	wg.Wait()
+
+	m.Logger.Info("解密完成", "成功", len(jobs)-len(failed), "失败", len(failed), "耗时", time.Since(begin))
+	if len(failed) > 0 {
+		slices.Sort(failed)
+		return fmt.Errorf("%d of %d files failed: %s", len(failed), len(jobs), strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+// decryptJob is an encrypted file and the directory its output goes to, an
+// existing output file is only replaced with force.
+type decryptJob struct {
+	src   string
+	dir   string
+	force bool
+}
+
+// output returns the decrypted file, or the folder of an encrypted zip.
+func (j decryptJob) output() string {
+	out := filepath.Join(j.dir, filepath.Base(strings.TrimSuffix(j.src, Aes256Suffix)))
+	if strings.HasSuffix(out, ".zip") {
+		return strings.TrimSuffix(out, ".zip") + string(os.PathSeparator)
+	}
+	return out
+}
+
+// run decrypts the file, secret returns the passphrase or the key for the kdf
+// of the header. With removeSrc the source is removed once the output is
+// verified.
+func (j decryptJob) run(secret func(kdf byte) (string, error), removeSrc bool, shredPasses int) error {
+	if err := os.MkdirAll(j.dir, 0o755); err != nil {
+		return err
+	}
+	outputFile := filepath.Join(j.dir, filepath.Base(strings.TrimSuffix(j.src, Aes256Suffix)))
+	isZip := strings.HasSuffix(outputFile, ".zip")
+	outputFolder := strings.TrimSuffix(outputFile, ".zip")
+	// The header tells whether a passphrase or the key is needed, files
+	// without one were written by older versions with rice.
+	isContainer, err := isContainerFile(j.src)
+	if err != nil {
+		return err
+	}
+	if !isZip && !j.force && rice.PathExists(outputFile) {
+		return fmt.Errorf("%s already exists, use --force to overwrite", outputFile)
+	}
+
+	switch {
+	case isContainer && isZip:
+		// 直接从加密文件中解压, 明文 ZIP 不落盘
+		r, size, closer, err := openContainerFile(j.src, secret)
+		if err != nil {
+			return err
+		}
+		err = UnzipFolder(r, size, outputFolder, j.force)
+		closer.Close()
+		if err != nil {
+			return err
+		}
+		if removeSrc {
+			if _, err := verifyContainerZip(j.src, outputFolder, secret); err != nil {
+				return fmt.Errorf("verify err: %w, source kept", err)
+			}
+		}
+
+	case isContainer:
+		if err := decryptContainerFile(j.src, outputFile, secret); err != nil {
+			return err
+		}
+		if removeSrc {
+			if err := verifyContainerFile(j.src, outputFile, secret); err != nil {
+				return fmt.Errorf("verify err: %w, source kept", err)
 			}
+		}
 
-			if err := os.Remove(outputFile); err != nil {
+	default:
+		key, err := secret(_kdfKey)
+		if err != nil {
+			return err
+		}
+		// 旧格式由 rice 解密, AES-GCM 已校验解密结果, 先写入临时文件
+		tmp, err := os.CreateTemp(j.dir, "."+filepath.Base(outputFile)+".*.tmp")
+		if err != nil {
+			return err
+		}
+		tmp.Close()
+		defer os.Remove(tmp.Name())
+		if err := rice.AESGCMDecryptFile(key, j.src, tmp.Name()); err != nil {
+			return err
+		}
+		if isZip {
+			// 旧格式只能先解密出 ZIP 再解压
+			if err := unzipFile(tmp.Name(), outputFolder, j.force); err != nil {
 				return err
 			}
+			if removeSrc {
+				if err := verifyZipFile(tmp.Name(), outputFolder); err != nil {
+					return fmt.Errorf("verify err: %w, source kept", err)
+				}
+			}
+		} else if err := os.Rename(tmp.Name(), outputFile); err != nil {
+			return err
 		}
+	}
 
-		err := os.Remove(file)
-		m.Logger.Info("解密完成", "耗时", time.Since(begin))
-		return err
+	if !removeSrc {
+		return nil
 	}
+	return removeSource(j.src, shredPasses)
+}
 
-	return nil
+// secretCache asks for the key and the passphrase only once, so decrypting a
+// folder does not prompt for every file.
+type secretCache struct {
+	cmd    *cobra.Command
+	mu     sync.Mutex
+	values map[byte]string
+}
+
+func (c *secretCache) get(kdf byte) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if s, ok := c.values[kdf]; ok {
+		return s, nil
+	}
+	var (
+		s   string
+		err error
+	)
+	if kdf == _kdfArgon2id {
+		s, err = readSecret("密码: ", false)
+	} else {
+		s, err = aesKeyFromFlags(c.cmd)
+	}
+	if err != nil {
+		return "", err
+	}
+	c.values[kdf] = s
+	return s, nil
 }
 
 func (m *PwdGenCLI) KillProcess(cmd *cobra.Command, args []string) error {
@@ -633,9 +1090,94 @@ func NewCLI() *cobra.Command {
 		Short: "生成随机密码",
 		Run:   muCLI.Root,
 	}
-	rootCmd.Flags().IntP("length", "n", 16, "生成的密码长度, [6, 2048]")
-	rootCmd.Flags().IntP("level", "l", 4, "生成的密码强度等级, 数字越大, 强度越高, [1, 4]")
-	rootCmd.Flags().IntP("output", "o", 1, "输出方式, 1: 剪贴板, 2: 控制台")
+	addPolicyFlags(rootCmd)
+	addOutputFlags(rootCmd, 1)
+	rootCmd.Flags().StringP("mask", "m", "", "按模板生成, 如 Aaaa-9999-!!!! 或 ?u?l?l?d?d?s, a/A/9/!/* 为小写/大写/数字/符号/任意, [a-f0-9] 为自定义字符集, \\ 转义, 其他字符原样保留, 不能与长度、等级和 --min-*/--max-* 同时使用")
+	rootCmd.Flags().Int("count", 1, "生成的密码个数, 大于 1 时逐行输出到控制台")
+	rootCmd.Flags().String("format", "plain", "批量输出格式, plain: 每行一个密码, json: 每行一个 JSON 对象, csv")
+
+	passphraseCmd := &cobra.Command{
+		Use:   "passphrase",
+		Short: "从词表中随机选词生成口令 (diceware)",
+		RunE:  muCLI.Passphrase,
+	}
+	passphraseCmd.Flags().IntP("words", "w", 6, "单词个数, [1, 64]")
+	passphraseCmd.Flags().StringP("separator", "s", "-", "单词之间的分隔符")
+	passphraseCmd.Flags().BoolP("capitalize", "c", false, "每个单词首字母大写")
+	passphraseCmd.Flags().BoolP("digit", "d", false, "在随机一个单词后追加一个数字")
+	passphraseCmd.Flags().Bool("symbol", false, "在随机一个单词后追加一个符号")
+	passphraseCmd.Flags().String("wordlist", "", "自定义词表文件, 每行一个单词, 默认使用内置的 EFF large wordlist")
+	addOutputFlags(passphraseCmd, 1)
+
+	pinyinCmd := &cobra.Command{
+		Use:   "pinyin [汉字短语]",
+		Short: "把汉字短语或随机常用字转为拼音密码, 便于按汉字记忆",
+		RunE:  muCLI.Pinyin,
+	}
+	pinyinCmd.Flags().IntP("chars", "n", 6, "未指定短语时随机选取的汉字个数, [1, 64]")
+	pinyinCmd.Flags().BoolP("tone", "t", true, "在每个拼音后追加声调数字")
+	pinyinCmd.Flags().BoolP("capitalize", "c", true, "每个拼音首字母大写")
+	pinyinCmd.Flags().StringP("separator", "s", "", "拼音之间的分隔符")
+	addOutputFlags(pinyinCmd, 1)
+
+	checkCmd := &cobra.Command{
+		Use:   "check",
+		Short: "检查密码强度, 从终端输入、标准输入或剪贴板读取密码",
+		Args:  cobra.NoArgs,
+		RunE:  muCLI.Check,
+	}
+	checkCmd.Flags().BoolP("clipboard", "c", false, "从剪贴板读取密码")
+
+	pwnedCmd := &cobra.Command{
+		Use:   "pwned",
+		Short: "在本地的 Have I Been Pwned 数据集中离线检查密码是否已泄露",
+		Args:  cobra.NoArgs,
+		RunE:  muCLI.Pwned,
+	}
+	pwnedCmd.Flags().String("db", "", "HIBP 数据集, 按哈希排序的 SHA-1 文件, 或 range 文件 (ABCDE.txt) 所在的目录")
+	pwnedCmd.Flags().StringP("batch", "b", "", "批量检查 xykey json 或浏览器导出的 csv 文件中的所有密码")
+	pwnedCmd.Flags().BoolP("clipboard", "c", false, "从剪贴板读取密码")
+	_ = pwnedCmd.MarkFlagRequired("db")
+
+	deriveCmd := &cobra.Command{
+		Use:   "derive",
+		Short: "由主密码、站点、账号和计数器确定性地生成站点密码, 无需保存",
+		Args:  cobra.NoArgs,
+		RunE:  muCLI.Derive,
+	}
+	addPolicyFlags(deriveCmd)
+	deriveCmd.Flags().StringP("site", "s", "", "站点, 如 github.com")
+	deriveCmd.Flags().StringP("login", "u", "", "登录账号")
+	deriveCmd.Flags().Uint32P("counter", "c", 1, "计数器, 需要更换密码时加 1")
+	deriveCmd.Flags().Bool("clipboard", false, "从剪贴板读取主密码")
+	addOutputFlags(deriveCmd, 1)
+	_ = deriveCmd.MarkFlagRequired("site")
+
+	otpCmd := &cobra.Command{
+		Use:   "otp [secret|otpauth://...]",
+		Short: "生成 TOTP/HOTP 验证码, 未指定参数时从终端读取 secret",
+		Args:  cobra.MaximumNArgs(1),
+		RunE:  muCLI.OTP,
+	}
+	otpCmd.PersistentFlags().String("algorithm", "sha1", "哈希算法, sha1, sha256 或 sha512")
+	otpCmd.PersistentFlags().Int("digits", 6, "验证码位数, 6 或 8")
+	otpCmd.PersistentFlags().Uint("period", 30, "TOTP 验证码的有效期, 单位: 秒")
+	otpCmd.Flags().Uint64("counter", 0, "HOTP 计数器, 指定后按 HOTP 生成")
+	addOutputFlags(otpCmd, 2)
+
+	otpNewCmd := &cobra.Command{
+		Use:   "new",
+		Short: "生成新的 TOTP/HOTP secret 并输出 otpauth:// URI",
+		Args:  cobra.NoArgs,
+		RunE:  muCLI.OTPNew,
+	}
+	otpNewCmd.Flags().String("issuer", "pwdgen", "签发者, 显示在验证器中")
+	otpNewCmd.Flags().String("account", "", "账号, 显示在验证器中")
+	otpNewCmd.Flags().Uint("secret-size", 20, "secret 的字节数")
+	otpNewCmd.Flags().Bool("hotp", false, "生成 HOTP secret, 默认为 TOTP")
+	otpNewCmd.Flags().String("qr", "", "输出 otpauth:// URI 的二维码, term: 在终端显示, 其他值: 保存为 png 文件的路径")
+	_ = otpNewCmd.MarkFlagRequired("account")
+	otpCmd.AddCommand(otpNewCmd)
 
 	csv2XykeyCmd := &cobra.Command{
 		Use:   "csv2xykey",
@@ -657,19 +1199,34 @@ func NewCLI() *cobra.Command {
 		RunE:  muCLI.EncryptFile,
 	}
 	encryptFileCmd.Flags().BoolP("genkey", "g", false, "生成一个 AES256 密钥")
-	encryptFileCmd.Flags().StringP("key", "k", "", "加密所需的密钥。如果不指定，则从环境变量 \"MEI_AES_KEY\" 中获取")
+	encryptFileCmd.Flags().StringP("key", "k", "", "加密所需的密钥或 24 个单词的助记词。如果不指定，则从环境变量 \"MEI_AES_KEY\" 中获取")
+	encryptFileCmd.Flags().Bool("mnemonic", false, "从终端输入助记词作为密钥")
+	encryptFileCmd.Flags().BoolP("passphrase", "p", false, "从终端输入密码, 用 Argon2id 派生密钥, 解密时自动识别")
+	encryptFileCmd.MarkFlagsMutuallyExclusive("passphrase", "key", "mnemonic")
 	encryptFileCmd.Flags().StringP("file", "f", "", "要加密的文件或文件夹")
 	encryptFileCmd.Flags().StringP("text", "t", "", "要加密的文本")
 	encryptFileCmd.Flags().String("output-dir", ".", "加密输出目录，默认当前目录")
-	encryptFileCmd.Flags().StringP("ignore", "i", "", "ignore 文件【暂未实现】")
+	encryptFileCmd.Flags().StringArrayP("ignore", "i", nil, "加密文件夹时忽略的 gitignore 格式规则, 可重复指定, 如 -i node_modules/ -i '*.log' -i '!keep.log'")
+	encryptFileCmd.Flags().StringArray("ignore-file", nil, "gitignore 格式的 ignore 文件, 可重复指定。文件夹根目录下的 "+_defaultIgnoreFile+" 会自动读取")
+	encryptFileCmd.Flags().Bool("remove-source", false, "加密后解密校验 SHA-256, 一致才删除源文件。默认保留源文件")
+	encryptFileCmd.Flags().Int("shred", 0, "删除源文件前用随机数据覆写的次数, 需要 --remove-source")
+	encryptFileCmd.Flags().Bool("force", false, "覆盖已存在的加密文件, 它可能是某些数据仅剩的副本")
+	encryptFileCmd.Flags().String("qr", "", "输出加密文本的二维码, term: 在终端显示, 其他值: 保存为 png 文件的路径")
 
 	decryptFileCmd := &cobra.Command{
 		Use:   "decrypt",
 		Short: "解密文本或文件",
 		RunE:  muCLI.DecryptFile,
 	}
-	decryptFileCmd.Flags().StringP("key", "k", "", "解密所需的密钥。如果不指定，则从环境变量 \"MEI_AES_KEY\" 中获取")
-	decryptFileCmd.Flags().StringP("file", "f", "", "要解密的文件或文件夹")
+	decryptFileCmd.Flags().StringP("key", "k", "", "解密所需的密钥或 24 个单词的助记词。如果不指定，则从环境变量 \"MEI_AES_KEY\" 中获取")
+	decryptFileCmd.Flags().Bool("mnemonic", false, "从终端输入助记词作为密钥")
+	decryptFileCmd.Flags().StringP("file", "f", "", "要解密的文件, 或文件夹 (递归解密其中所有的 .aes256 文件)")
+	decryptFileCmd.Flags().String("output-dir", "", "解密输出目录, 默认与加密文件相同。解密文件夹时保留目录结构")
+	decryptFileCmd.Flags().IntP("workers", "j", min(runtime.NumCPU(), 4), "解密文件夹时并行解密的文件数")
+	decryptFileCmd.Flags().Bool("remove-source", false, "解密后校验输出, 一致才删除加密文件。默认保留加密文件")
+	decryptFileCmd.Flags().Int("shred", 0, "删除加密文件前用随机数据覆写的次数, 需要 --remove-source")
+	decryptFileCmd.Flags().Bool("dry-run", false, "只列出将要生成的文件, 不解密")
+	decryptFileCmd.Flags().Bool("force", false, "覆盖已存在的输出文件, 默认跳过该加密文件并报错")
 	decryptFileCmd.Flags().StringP("text", "t", "", "要解密的文本")
 	decryptFileCmd.MarkFlagsOneRequired("file", "text")
 
@@ -687,6 +1244,15 @@ func NewCLI() *cobra.Command {
 	}
 	miNoteExportCmd.Flags().StringP("format", "f", "json", "导出格式, 目前仅支持 json")
 
+	clipClearCmd := &cobra.Command{
+		Use:    _clipClearCmd,
+		Short:  "等待一段时间后清除剪贴板中的密码, 由其他命令在后台调用",
+		Hidden: true,
+		Args:   cobra.NoArgs,
+		RunE:   muCLI.ClipClear,
+	}
+	clipClearCmd.Flags().Duration("after", 30*time.Second, "等待时间")
+
 	versionCmd := &cobra.Command{
 		Use:   "version",
 		Short: "版本",
@@ -699,13 +1265,219 @@ func NewCLI() *cobra.Command {
 		},
 	}
 
+	pinCmd := &cobra.Command{
+		Use:   "pin",
+		Short: "生成数字 PIN, 排除连续、重复、日期和常见的 PIN",
+		Args:  cobra.NoArgs,
+		RunE:  muCLI.Pin,
+	}
+	pinCmd.Flags().IntP("length", "n", 6, "PIN 的长度, [4, 12]")
+	pinCmd.Flags().Int("count", 1, "生成的个数, 大于 1 时逐行输出到控制台")
+	addOutputFlags(pinCmd, 1)
+
+	hashCmd := &cobra.Command{
+		Use:   "hash",
+		Short: "计算密码哈希 (PHC 格式), 从终端或剪贴板读取密码, 标准输入为管道时逐行计算",
+		Args:  cobra.NoArgs,
+		RunE:  muCLI.Hash,
+	}
+	hashCmd.Flags().StringP("algorithm", "a", "argon2id", "哈希算法, "+strings.Join(hashAlgorithms, ", "))
+	hashCmd.Flags().Int("cost", 0, "bcrypt cost, 默认 10")
+	hashCmd.Flags().Uint32("time", 0, "argon2id 迭代次数, 默认 3")
+	hashCmd.Flags().Uint32("memory", 0, "argon2id 内存, 单位: KiB, 默认 65536")
+	hashCmd.Flags().Int("parallelism", 0, "argon2id 线程数 (默认 4) 或 scrypt 的 p (默认 1)")
+	hashCmd.Flags().Int("ln", 0, "scrypt 的 log2(N), 默认 15")
+	hashCmd.Flags().Int("block-size", 0, "scrypt 的 r, 默认 8")
+	hashCmd.Flags().Int("iterations", 0, "PBKDF2 迭代次数, 默认 sha256: 600000, sha512: 210000")
+	hashCmd.Flags().Int("salt-size", 0, "盐的字节数, [8, 1024], 默认 16")
+	hashCmd.Flags().Int("key-size", 0, "哈希的字节数, [16, 1024], 默认 32")
+	hashCmd.Flags().Duration("benchmark", 0, "调整参数使一次哈希至少耗时指定时间, 如 500ms, 只输出调整后的参数")
+	hashCmd.Flags().Bool("with-password", false, "逐行计算时同时输出密码, 以 tab 分隔")
+	hashCmd.Flags().BoolP("clipboard", "c", false, "从剪贴板读取密码")
+
+	verifyCmd := &cobra.Command{
+		Use:   "verify <hash>",
+		Short: "校验密码与 bcrypt、scrypt、argon2id 或 PBKDF2 哈希是否匹配",
+		Args:  cobra.ExactArgs(1),
+		RunE:  muCLI.Verify,
+	}
+	verifyCmd.Flags().BoolP("clipboard", "c", false, "从剪贴板读取密码")
+
+	jwtCmd := &cobra.Command{
+		Use:   "jwt",
+		Short: "解析、签名和校验 JWT",
+	}
+	jwtDecodeCmd := &cobra.Command{
+		Use:   "decode [token]",
+		Short: "解析 JWT, 输出 header 和 claims 并检查 exp/nbf/iat, 不校验签名",
+		Args:  cobra.MaximumNArgs(1),
+		RunE:  muCLI.JWTDecode,
+	}
+	jwtSignCmd := &cobra.Command{
+		Use:   "sign [claims json]",
+		Short: "用密钥文件签发 JWT, 如 pwdgen jwt sign --key key.pem -a ES256 '{\"sub\":\"1\"}'",
+		Args:  cobra.MaximumNArgs(1),
+		RunE:  muCLI.JWTSign,
+	}
+	jwtSignCmd.Flags().StringP("alg", "a", "HS256", "签名算法, "+strings.Join(jwtAlgorithms, ", "))
+	jwtSignCmd.Flags().StringP("key", "k", "", "密钥文件, HS256 为密钥文本, 其他为 PEM 格式的私钥")
+	jwtSignCmd.Flags().String("kid", "", "header 中的 kid")
+	jwtSignCmd.Flags().Duration("exp", 0, "有效期, 如 1h, 为 0 时不设置 exp")
+	addOutputFlags(jwtSignCmd, 2)
+	_ = jwtSignCmd.MarkFlagRequired("key")
+	jwtVerifyCmd := &cobra.Command{
+		Use:   "verify [token]",
+		Short: "校验 JWT 的签名和 exp/nbf/iat",
+		Args:  cobra.MaximumNArgs(1),
+		RunE:  muCLI.JWTVerify,
+	}
+	jwtVerifyCmd.Flags().StringP("alg", "a", "HS256", "签名算法, 只接受该算法签名的 JWT, "+strings.Join(jwtAlgorithms, ", "))
+	jwtVerifyCmd.Flags().StringP("key", "k", "", "密钥文件, HS256 为密钥文本, 其他为 PEM 格式的公钥")
+	jwtVerifyCmd.Flags().Duration("leeway", 0, "校验时间时允许的时钟误差")
+	_ = jwtVerifyCmd.MarkFlagRequired("key")
+	jwtSecretCmd := &cobra.Command{
+		Use:   "secret",
+		Short: "生成 HMAC 密钥",
+		Args:  cobra.NoArgs,
+		RunE:  muCLI.JWTSecret,
+	}
+	jwtSecretCmd.Flags().IntP("bytes", "b", 32, "密钥的字节数, [32, 1024]")
+	jwtSecretCmd.Flags().StringP("encoding", "e", "base64url", "编码, base64url 或 hex")
+	addOutputFlags(jwtSecretCmd, 2)
+	jwtCmd.AddCommand(jwtDecodeCmd, jwtSignCmd, jwtVerifyCmd, jwtSecretCmd)
+
+	keygenCmd := &cobra.Command{
+		Use:       "keygen [ed25519|rsa|wireguard|age]",
+		Short:     "生成 OpenSSH 格式的 ed25519/RSA 密钥对, 或 WireGuard、age 使用的 X25519 密钥对, 默认为 ed25519",
+		Args:      cobra.MatchAll(cobra.MaximumNArgs(1), cobra.OnlyValidArgs),
+		ValidArgs: keyTypes,
+		RunE:      muCLI.Keygen,
+	}
+	keygenCmd.Flags().StringP("file", "f", "", "私钥文件, 默认 id_ed25519、id_rsa、wg.key 或 age.key, 公钥写入加上 .pub 后缀的文件")
+	keygenCmd.Flags().StringP("comment", "C", "", "SSH 公钥的注释, 默认为 user@hostname")
+	keygenCmd.Flags().IntP("bits", "b", 4096, "RSA 密钥长度")
+	keygenCmd.Flags().BoolP("passphrase", "p", false, "从终端输入 SSH 私钥的密码")
+	keygenCmd.Flags().Bool("gen-passphrase", false, "随机生成 SSH 私钥的密码 (diceware, 6 个单词) 并按 --output 输出")
+	keygenCmd.Flags().Bool("force", false, "覆盖已存在的文件")
+	addOutputFlags(keygenCmd, 1)
+
+	certCmd := &cobra.Command{
+		Use:   "cert",
+		Short: "创建本地根 CA 并签发 TLS 证书",
+	}
+	certCACmd := &cobra.Command{
+		Use:   "ca",
+		Short: "创建本地根 CA",
+		Args:  cobra.NoArgs,
+		RunE:  muCLI.CertCA,
+	}
+	certCACmd.Flags().String("out", "ca", "输出文件的前缀, 写入 <out>.pem 和 <out>-key.pem")
+	certCACmd.Flags().String("cn", "pwdgen local CA", "CA 的名称 (Common Name)")
+	certCACmd.Flags().Int("days", 3650, "有效期, 单位: 天")
+	certIssueCmd := &cobra.Command{
+		Use:   "issue <dns|ip>...",
+		Short: "为域名和 IP 签发 TLS 证书, 如 pwdgen cert issue localhost 127.0.0.1 ::1",
+		Args:  cobra.MinimumNArgs(1),
+		RunE:  muCLI.CertIssue,
+	}
+	certIssueCmd.Flags().String("ca", "ca", "CA 文件的前缀, 读取 <ca>.pem 和 <ca>-key.pem")
+	certIssueCmd.Flags().Bool("self-signed", false, "不使用 CA, 生成自签名证书")
+	certIssueCmd.Flags().String("out", "", "输出文件的前缀, 默认为第一个域名或 IP")
+	certIssueCmd.Flags().Int("days", 397, "有效期, 单位: 天, 浏览器不接受超过 398 天的证书")
+	certIssueCmd.Flags().Bool("client", false, "同时用于客户端认证 (mTLS)")
+	certIssueCmd.Flags().StringP("format", "f", "pem", "输出格式, pem: <out>.pem 和 <out>-key.pem, p12: <out>.p12, 随机生成密码并按 --output 输出")
+	certIssueCmd.Flags().Bool("legacy", false, "p12 使用旧的 3DES 加密, 兼容旧版本的 Windows、macOS 和 Java")
+	addOutputFlags(certIssueCmd, 1)
+	for _, c := range []*cobra.Command{certCACmd, certIssueCmd} {
+		c.Flags().String("key-type", "ecdsa", "密钥类型, "+strings.Join(certKeyTypes, ", "))
+		c.Flags().IntP("bits", "b", 3072, "RSA 密钥长度")
+		c.Flags().Bool("force", false, "覆盖已存在的文件")
+	}
+	certCmd.AddCommand(certCACmd, certIssueCmd)
+
+	shamirCmd := &cobra.Command{
+		Use:   "shamir",
+		Short: "用 Shamir 秘密共享拆分和恢复密钥",
+	}
+	shamirSplitCmd := &cobra.Command{
+		Use:   "split",
+		Short: "把 hex 密钥或助记词拆分为 n 份, 任意 k 份即可恢复, 从终端、标准输入或剪贴板读取。其他秘密 (如密码) 使用 --text",
+		Args:  cobra.NoArgs,
+		RunE:  muCLI.ShamirSplit,
+	}
+	shamirSplitCmd.Flags().IntP("shares", "n", 5, "拆分的份数, [2, 255]")
+	shamirSplitCmd.Flags().IntP("threshold", "k", 3, "恢复所需的份数, [2, n]")
+	shamirSplitCmd.Flags().BoolP("words", "w", false, "以单词输出, 便于抄写")
+	shamirSplitCmd.Flags().BoolP("clipboard", "c", false, "从剪贴板读取密钥")
+	shamirSplitCmd.Flags().Bool("text", false, "按原样拆分任意文本, 而不是 hex 密钥或助记词, 恢复时原样输出")
+	shamirCombineCmd := &cobra.Command{
+		Use:   "combine [share]...",
+		Short: "由拆分的密钥恢复, 密钥输出为 hex, --text 拆分的秘密原样输出, 未指定参数时从标准输入逐行读取",
+		RunE:  muCLI.ShamirCombine,
+	}
+	addOutputFlags(shamirCombineCmd, 2)
+	shamirCmd.AddCommand(shamirSplitCmd, shamirCombineCmd)
+
+	mnemonicCmd := &cobra.Command{
+		Use:   "mnemonic",
+		Short: "在 AES key 和 BIP39 助记词之间转换",
+	}
+	mnemonicEncodeCmd := &cobra.Command{
+		Use:   "encode",
+		Short: "把 hex 格式的 key 转为助记词, 32 字节的 key 为 24 个单词",
+		Args:  cobra.NoArgs,
+		RunE:  muCLI.MnemonicEncode,
+	}
+	mnemonicDecodeCmd := &cobra.Command{
+		Use:   "decode",
+		Short: "把助记词转回 hex 格式的 key, 并校验",
+		Args:  cobra.NoArgs,
+		RunE:  muCLI.MnemonicDecode,
+	}
+	for _, c := range []*cobra.Command{mnemonicEncodeCmd, mnemonicDecodeCmd} {
+		c.Flags().BoolP("clipboard", "c", false, "从剪贴板读取")
+		addOutputFlags(c, 2)
+	}
+	mnemonicCmd.AddCommand(mnemonicEncodeCmd, mnemonicDecodeCmd)
+
+	tokenCmd := &cobra.Command{
+		Use:       "token [hex|base32|base64url|base58|uuid|uuidv7|ulid|xid|nanoid|snowflake]",
+		Short:     "生成随机 API token 或 UUID、ULID、xid、nanoid、雪花 ID, 默认为 hex",
+		Args:      cobra.MatchAll(cobra.MaximumNArgs(1), cobra.OnlyValidArgs),
+		ValidArgs: tokenKinds,
+		RunE:      muCLI.Token,
+	}
+	tokenCmd.Flags().IntP("bytes", "b", 32, "随机 token 的字节数, [8, 1024]")
+	tokenCmd.Flags().StringP("prefix", "p", "", "token 前缀, 如 ghp_")
+	tokenCmd.Flags().Bool("checksum", false, "追加 6 位 base62 的 CRC32 校验码, 与 GitHub token 相同")
+	tokenCmd.Flags().Int("length", 21, "nanoid 的长度")
+	tokenCmd.Flags().Uint16("worker-id", 1, "雪花 ID 的机器码, [0, 63]")
+	tokenCmd.Flags().Int("count", 1, "生成的个数, 大于 1 时逐行输出到控制台")
+	addOutputFlags(tokenCmd, 2)
+
 	rootCmd.AddCommand(
+		passphraseCmd,
+		pinyinCmd,
+		checkCmd,
+		pwnedCmd,
+		deriveCmd,
+		otpCmd,
+		pinCmd,
+		tokenCmd,
+		hashCmd,
+		verifyCmd,
+		jwtCmd,
+		keygenCmd,
+		certCmd,
+		shamirCmd,
+		mnemonicCmd,
 		csv2XykeyCmd,
 		splitFileCmd,
 		encryptFileCmd,
 		decryptFileCmd,
 		killCmd,
 		versionCmd,
+		clipClearCmd,
 		miNoteExportCmd,
 	)
 	return rootCmd
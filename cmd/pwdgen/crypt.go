@@ -0,0 +1,614 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sync"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/hkdf"
+)
+
+// Encrypted files start with a header that holds everything needed to derive
+// the key again:
+//
+//	v1: magic "MEIE" | 1 | kdf | time u32 | memory u32 | threads u8 | salt | nonce
+//	v2: magic "MEIE" | 2 | kdf | [time u32 | memory u32 | threads u8] | salt | chunk size u32 | nonce prefix
+//
+// v1 is a single AES-256-GCM message. v2 splits the plaintext into chunks
+// sealed with the STREAM construction, the nonce of a chunk is the prefix, a
+// big endian counter and a flag set on the last chunk, so truncated and
+// reordered chunks fail to open. The header is the additional data of every
+// chunk. Files of rice.AESGCMEncryptFile have no header at all.
+const (
+	_containerMagic   = "MEIE"
+	_containerVersion = 2
+
+	_kdfKey      = 0
+	_kdfArgon2id = 1
+
+	_encryptTime    = 3
+	_encryptMemory  = 64 * 1024
+	_encryptThreads = 4
+	_encryptSalt    = 16
+
+	_chunkSize    = 64 * 1024
+	_maxChunkSize = 16 * 1024 * 1024
+	_noncePrefix  = 7
+)
+
+var (
+	errNotContainer = errors.New("not a pwdgen encrypted file")
+	errOpenChunk    = errors.New("wrong key or passphrase, or the file is truncated or corrupted")
+	errAESKey       = errors.New("key must be 32 bytes of hex, generate one with encrypt -g, or use -p for a passphrase")
+)
+
+// parseAESKey decodes the key of --key, --mnemonic or MEI_AES_KEY. It is used
+// as is, a secret chosen by a human belongs to the passphrase mode.
+func parseAESKey(secret string) ([]byte, error) {
+	key, err := hex.DecodeString(secret)
+	if err != nil || len(key) != 32 {
+		return nil, errAESKey
+	}
+	return key, nil
+}
+
+type containerHeader struct {
+	Version byte
+	KDF     byte
+	// Argon2id parameters, only for _kdfArgon2id.
+	Time    uint32
+	Memory  uint32
+	Threads uint8
+	Salt    []byte
+	// ChunkSize is the plaintext size of a chunk, v2 only.
+	ChunkSize uint32
+	// Nonce is the GCM nonce in v1 and the nonce prefix in v2.
+	Nonce []byte
+}
+
+func newContainerHeader(reader io.Reader, kdf byte) (containerHeader, error) {
+	h := containerHeader{
+		Version:   _containerVersion,
+		KDF:       kdf,
+		Salt:      make([]byte, _encryptSalt),
+		ChunkSize: _chunkSize,
+		Nonce:     make([]byte, _noncePrefix),
+	}
+	if kdf == _kdfArgon2id {
+		h.Time, h.Memory, h.Threads = _encryptTime, _encryptMemory, _encryptThreads
+	}
+	if _, err := io.ReadFull(reader, h.Salt); err != nil {
+		return h, err
+	}
+	if _, err := io.ReadFull(reader, h.Nonce); err != nil {
+		return h, err
+	}
+	return h, nil
+}
+
+func (h containerHeader) marshal() []byte {
+	b := []byte(_containerMagic)
+	b = append(b, h.Version, h.KDF)
+	if h.Version == 1 || h.KDF == _kdfArgon2id {
+		b = binary.BigEndian.AppendUint32(b, h.Time)
+		b = binary.BigEndian.AppendUint32(b, h.Memory)
+		b = append(b, h.Threads)
+	}
+	b = append(b, h.Salt...)
+	if h.Version >= 2 {
+		b = binary.BigEndian.AppendUint32(b, h.ChunkSize)
+	}
+	return append(b, h.Nonce...)
+}
+
+// readContainerHeader reads the header, errNotContainer is returned when r
+// does not start with the magic, e.g. files of rice.AESGCMEncryptFile.
+func readContainerHeader(r io.Reader) (containerHeader, error) {
+	var h containerHeader
+	fixed := make([]byte, len(_containerMagic)+2)
+	if _, err := io.ReadFull(r, fixed); err != nil {
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return h, errNotContainer
+		}
+		return h, err
+	}
+	if string(fixed[:len(_containerMagic)]) != _containerMagic {
+		return h, errNotContainer
+	}
+	h.Version, h.KDF = fixed[len(_containerMagic)], fixed[len(_containerMagic)+1]
+	if h.Version < 1 || h.Version > _containerVersion {
+		return h, fmt.Errorf("unsupported version %d, upgrade pwdgen", h.Version)
+	}
+	if h.KDF != _kdfArgon2id && (h.Version == 1 || h.KDF != _kdfKey) {
+		return h, fmt.Errorf("unsupported kdf %d", h.KDF)
+	}
+
+	if h.KDF == _kdfArgon2id {
+		params := make([]byte, 9)
+		if _, err := io.ReadFull(r, params); err != nil {
+			return h, err
+		}
+		h.Time = binary.BigEndian.Uint32(params)
+		h.Memory = binary.BigEndian.Uint32(params[4:])
+		h.Threads = params[8]
+		if h.Time < 1 || h.Time > 16 || h.Threads < 1 || h.Memory < 8*uint32(h.Threads) || h.Memory > 4*1024*1024 {
+			return h, errors.New("invalid kdf parameters")
+		}
+	}
+	h.Salt = make([]byte, _encryptSalt)
+	if _, err := io.ReadFull(r, h.Salt); err != nil {
+		return h, err
+	}
+	h.Nonce = make([]byte, 12)
+	if h.Version >= 2 {
+		var size [4]byte
+		if _, err := io.ReadFull(r, size[:]); err != nil {
+			return h, err
+		}
+		h.ChunkSize = binary.BigEndian.Uint32(size[:])
+		if h.ChunkSize < 1024 || h.ChunkSize > _maxChunkSize {
+			return h, errors.New("invalid chunk size")
+		}
+		h.Nonce = make([]byte, _noncePrefix)
+	}
+	if _, err := io.ReadFull(r, h.Nonce); err != nil {
+		return h, err
+	}
+	return h, nil
+}
+
+// aead derives the AES key from the secret, a passphrase for _kdfArgon2id
+// and the key of --key or MEI_AES_KEY for _kdfKey.
+func (h containerHeader) aead(secret string) (cipher.AEAD, error) {
+	if secret == "" {
+		return nil, errors.New("passphrase or key is empty")
+	}
+	var key []byte
+	if h.KDF == _kdfArgon2id {
+		key = argon2.IDKey([]byte(secret), h.Salt, h.Time, h.Memory, h.Threads, 32)
+	} else {
+		ikm, err := parseAESKey(secret)
+		if err != nil {
+			return nil, err
+		}
+		key = make([]byte, 32)
+		if _, err := io.ReadFull(hkdf.New(sha256.New, ikm, h.Salt, []byte("pwdgen aes256 stream")), key); err != nil {
+			return nil, err
+		}
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func (h containerHeader) chunkNonce(counter uint32, last bool) []byte {
+	nonce := make([]byte, 0, 12)
+	nonce = append(nonce, h.Nonce...)
+	nonce = binary.BigEndian.AppendUint32(nonce, counter)
+	if last {
+		return append(nonce, 1)
+	}
+	return append(nonce, 0)
+}
+
+// streamWriter seals the plaintext in chunks, a full chunk is only written
+// once more data follows, so the last chunk is never empty unless the whole
+// plaintext is.
+type streamWriter struct {
+	w       io.Writer
+	h       containerHeader
+	aead    cipher.AEAD
+	header  []byte
+	counter uint32
+	buf     []byte
+	closed  bool
+}
+
+// newEncryptWriter writes the header to w, Close must be called to write the
+// last chunk.
+func newEncryptWriter(w io.Writer, h containerHeader, secret string) (io.WriteCloser, error) {
+	aead, err := h.aead(secret)
+	if err != nil {
+		return nil, err
+	}
+	header := h.marshal()
+	if _, err := w.Write(header); err != nil {
+		return nil, err
+	}
+	return &streamWriter{
+		w:      w,
+		h:      h,
+		aead:   aead,
+		header: header,
+		buf:    make([]byte, 0, int(h.ChunkSize)+aead.Overhead()),
+	}, nil
+}
+
+func (s *streamWriter) Write(p []byte) (int, error) {
+	if s.closed {
+		return 0, errors.New("write to closed stream")
+	}
+	n := len(p)
+	for len(p) > 0 {
+		if len(s.buf) == int(s.h.ChunkSize) {
+			if err := s.flush(false); err != nil {
+				return n - len(p), err
+			}
+		}
+		k := min(int(s.h.ChunkSize)-len(s.buf), len(p))
+		s.buf = append(s.buf, p[:k]...)
+		p = p[k:]
+	}
+	return n, nil
+}
+
+func (s *streamWriter) flush(last bool) error {
+	if s.counter == math.MaxUint32 {
+		return errors.New("too many chunks")
+	}
+	out := s.aead.Seal(s.buf[:0], s.h.chunkNonce(s.counter, last), s.buf, s.header)
+	if _, err := s.w.Write(out); err != nil {
+		return err
+	}
+	s.counter++
+	s.buf = s.buf[:0]
+	return nil
+}
+
+func (s *streamWriter) Close() error {
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+	return s.flush(true)
+}
+
+// streamReader opens the chunks of a streamWriter.
+type streamReader struct {
+	r       *bufio.Reader
+	h       containerHeader
+	aead    cipher.AEAD
+	header  []byte
+	counter uint32
+	buf     []byte
+	plain   []byte
+	done    bool
+}
+
+// newDecryptReader returns the plaintext of the container whose header was
+// already read from r. v1 containers are opened at once.
+func newDecryptReader(r *bufio.Reader, h containerHeader, secret string) (io.Reader, error) {
+	aead, err := h.aead(secret)
+	if err != nil {
+		return nil, err
+	}
+	if h.Version == 1 {
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return nil, err
+		}
+		plaintext, err := aead.Open(nil, h.Nonce, data, h.marshal())
+		if err != nil {
+			return nil, errOpenChunk
+		}
+		return bytes.NewReader(plaintext), nil
+	}
+	return &streamReader{
+		r:      r,
+		h:      h,
+		aead:   aead,
+		header: h.marshal(),
+		buf:    make([]byte, int(h.ChunkSize)+aead.Overhead()),
+	}, nil
+}
+
+func (s *streamReader) Read(p []byte) (int, error) {
+	for len(s.plain) == 0 {
+		if s.done {
+			return 0, io.EOF
+		}
+		if err := s.next(); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, s.plain)
+	s.plain = s.plain[n:]
+	return n, nil
+}
+
+func (s *streamReader) next() error {
+	n, err := io.ReadFull(s.r, s.buf)
+	last := false
+	switch {
+	case errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF):
+		last = true
+	case err != nil:
+		return err
+	default:
+		// A full chunk is the last one only at the end of the file.
+		if _, err := s.r.Peek(1); errors.Is(err, io.EOF) {
+			last = true
+		} else if err != nil {
+			return err
+		}
+	}
+	plain, err := s.aead.Open(s.buf[:0], s.h.chunkNonce(s.counter, last), s.buf[:n], s.header)
+	if err != nil {
+		return errOpenChunk
+	}
+	s.counter++
+	s.plain, s.done = plain, last
+	return nil
+}
+
+// isContainerFile reports whether the file starts with the container magic.
+func isContainerFile(path string) (bool, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+	_, err = readContainerHeader(bufio.NewReader(f))
+	if errors.Is(err, errNotContainer) {
+		return false, nil
+	}
+	return true, err
+}
+
+// encryptContainerFile encrypts src into dst, secret is a passphrase for
+// _kdfArgon2id and a key for _kdfKey.
+func encryptContainerFile(kdf byte, secret, src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	return encryptContainer(kdf, secret, dst, func(w io.Writer) error {
+		_, err := io.Copy(w, in)
+		return err
+	})
+}
+
+// encryptContainer encrypts whatever write writes into dst.
+func encryptContainer(kdf byte, secret, dst string, write func(w io.Writer) error) error {
+	return writeFileAtomic(dst, 0o644, func(w io.Writer) error {
+		h, err := newContainerHeader(rand.Reader, kdf)
+		if err != nil {
+			return err
+		}
+		enc, err := newEncryptWriter(w, h, secret)
+		if err != nil {
+			return err
+		}
+		if err := write(enc); err != nil {
+			return err
+		}
+		return enc.Close()
+	})
+}
+
+// decryptContainerFile decrypts src into dst, secret is called with the kdf
+// of the header to ask for the passphrase or the key.
+func decryptContainerFile(src, dst string, secret func(kdf byte) (string, error)) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	r := bufio.NewReaderSize(in, _chunkSize)
+	h, err := readContainerHeader(r)
+	if err != nil {
+		return err
+	}
+	s, err := secret(h.KDF)
+	if err != nil {
+		return err
+	}
+	dec, err := newDecryptReader(r, h, s)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(dst, 0o644, func(w io.Writer) error {
+		_, err := io.Copy(w, dec)
+		return err
+	})
+}
+
+// containerReaderAt gives random access to the plaintext of a v2 container,
+// only the chunk holding the offset is decrypted. archive/zip needs this to
+// read an encrypted zip without writing it to disk in plaintext.
+type containerReaderAt struct {
+	r      io.ReaderAt
+	h      containerHeader
+	aead   cipher.AEAD
+	header []byte
+	chunks int64
+	size   int64
+
+	mu    sync.Mutex
+	index int64
+	buf   []byte
+	plain []byte
+}
+
+func newContainerReaderAt(r io.ReaderAt, fileSize int64, h containerHeader, secret string) (*containerReaderAt, error) {
+	aead, err := h.aead(secret)
+	if err != nil {
+		return nil, err
+	}
+	header := h.marshal()
+	chunk := int64(h.ChunkSize) + int64(aead.Overhead())
+	body := fileSize - int64(len(header))
+	if body < int64(aead.Overhead()) {
+		return nil, errOpenChunk
+	}
+	chunks := (body + chunk - 1) / chunk
+	lastPlain := body - (chunks-1)*chunk - int64(aead.Overhead())
+	if lastPlain < 0 || chunks > math.MaxUint32 {
+		return nil, errOpenChunk
+	}
+	return &containerReaderAt{
+		r:      r,
+		h:      h,
+		aead:   aead,
+		header: header,
+		chunks: chunks,
+		size:   (chunks-1)*int64(h.ChunkSize) + lastPlain,
+		index:  -1,
+		buf:    make([]byte, chunk),
+	}, nil
+}
+
+// Size returns the plaintext size.
+func (c *containerReaderAt) Size() int64 {
+	return c.size
+}
+
+func (c *containerReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, errors.New("negative offset")
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	n := 0
+	for n < len(p) && off < c.size {
+		i := off / int64(c.h.ChunkSize)
+		if err := c.load(i); err != nil {
+			return n, err
+		}
+		k := copy(p[n:], c.plain[off-i*int64(c.h.ChunkSize):])
+		n += k
+		off += int64(k)
+	}
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+func (c *containerReaderAt) load(i int64) error {
+	if i == c.index {
+		return nil
+	}
+	chunk := int64(len(c.buf))
+	start := int64(len(c.header)) + i*chunk
+	buf := c.buf
+	if last := i == c.chunks-1; last {
+		buf = buf[:c.size-i*int64(c.h.ChunkSize)+int64(c.aead.Overhead())]
+	}
+	if _, err := c.r.ReadAt(buf, start); err != nil {
+		return err
+	}
+	c.index = -1
+	plain, err := c.aead.Open(buf[:0], c.h.chunkNonce(uint32(i), i == c.chunks-1), buf, c.header)
+	if err != nil {
+		return errOpenChunk
+	}
+	c.index, c.plain = i, plain
+	return nil
+}
+
+// openContainerFile opens the file for random access to its plaintext, v1
+// containers are decrypted into memory. secret works as in
+// decryptContainerFile.
+func openContainerFile(path string, secret func(kdf byte) (string, error)) (io.ReaderAt, int64, io.Closer, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, 0, nil, err
+	}
+	fail := func(err error) (io.ReaderAt, int64, io.Closer, error) {
+		f.Close()
+		return nil, 0, nil, err
+	}
+	r := bufio.NewReader(f)
+	h, err := readContainerHeader(r)
+	if err != nil {
+		return fail(err)
+	}
+	s, err := secret(h.KDF)
+	if err != nil {
+		return fail(err)
+	}
+	if h.Version == 1 {
+		dec, err := newDecryptReader(r, h, s)
+		if err != nil {
+			return fail(err)
+		}
+		f.Close()
+		br := dec.(*bytes.Reader)
+		return br, br.Size(), io.NopCloser(br), nil
+	}
+	info, err := f.Stat()
+	if err != nil {
+		return fail(err)
+	}
+	ra, err := newContainerReaderAt(f, info.Size(), h, s)
+	if err != nil {
+		return fail(err)
+	}
+	return ra, ra.Size(), f, nil
+}
+
+// encryptTextWithPassphrase returns the container as base64.
+func encryptTextWithPassphrase(text, passphrase string) (string, error) {
+	h, err := newContainerHeader(rand.Reader, _kdfArgon2id)
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	enc, err := newEncryptWriter(&buf, h, passphrase)
+	if err != nil {
+		return "", err
+	}
+	if _, err := io.WriteString(enc, text); err != nil {
+		return "", err
+	}
+	if err := enc.Close(); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(buf.Bytes()), nil
+}
+
+// decryptTextWithPassphrase decrypts the container of decodeContainerText.
+func decryptTextWithPassphrase(data []byte, passphrase string) (string, error) {
+	r := bufio.NewReader(bytes.NewReader(data))
+	h, err := readContainerHeader(r)
+	if err != nil {
+		return "", err
+	}
+	if h.KDF != _kdfArgon2id {
+		return "", errors.New("text is not encrypted with a passphrase")
+	}
+	dec, err := newDecryptReader(r, h, passphrase)
+	if err != nil {
+		return "", err
+	}
+	b, err := io.ReadAll(dec)
+	return string(b), err
+}
+
+// decodeContainerText returns the container of an encrypted text, ok is
+// false for texts of rice.AESGCMEncryptText.
+func decodeContainerText(text string) ([]byte, bool) {
+	data, err := base64.StdEncoding.DecodeString(text)
+	if err != nil || !bytes.HasPrefix(data, []byte(_containerMagic)) {
+		return nil, false
+	}
+	return data, true
+}
@@ -0,0 +1,251 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+const _testKey = "000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f"
+
+// sealTest encrypts data into a v2 container, argon2id runs with the
+// smallest parameters to keep the tests fast.
+func sealTest(t *testing.T, kdf byte, secret string, data []byte) []byte {
+	t.Helper()
+	h, err := newContainerHeader(rand.Reader, kdf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if kdf == _kdfArgon2id {
+		h.Time, h.Memory, h.Threads = 1, 64, 1
+	}
+	var buf bytes.Buffer
+	enc, err := newEncryptWriter(&buf, h, secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := enc.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	if err := enc.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// openStream decrypts the container with the streaming reader.
+func openStream(data []byte, secret string) ([]byte, error) {
+	r := bufio.NewReaderSize(bytes.NewReader(data), _chunkSize)
+	h, err := readContainerHeader(r)
+	if err != nil {
+		return nil, err
+	}
+	dec, err := newDecryptReader(r, h, secret)
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(dec)
+}
+
+// openRandom decrypts the container through containerReaderAt.
+func openRandom(data []byte, secret string) ([]byte, error) {
+	h, err := readContainerHeader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	ra, err := newContainerReaderAt(bytes.NewReader(data), int64(len(data)), h, secret)
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(io.NewSectionReader(ra, 0, ra.Size()))
+}
+
+func TestContainerRoundTrip(t *testing.T) {
+	secrets := map[byte]string{_kdfKey: _testKey, _kdfArgon2id: "correct horse"}
+	for kdf, secret := range secrets {
+		for _, size := range []int{0, 1, _chunkSize - 1, _chunkSize, _chunkSize + 1, 3*_chunkSize + 7} {
+			plain := make([]byte, size)
+			rand.Read(plain)
+			data := sealTest(t, kdf, secret, plain)
+
+			got, err := openStream(data, secret)
+			if err != nil || !bytes.Equal(got, plain) {
+				t.Errorf("kdf %d size %d: stream round trip failed: %v", kdf, size, err)
+			}
+			got, err = openRandom(data, secret)
+			if err != nil || !bytes.Equal(got, plain) {
+				t.Errorf("kdf %d size %d: random access round trip failed: %v", kdf, size, err)
+			}
+		}
+	}
+}
+
+func TestContainerTampered(t *testing.T) {
+	plain := make([]byte, 3*_chunkSize+100)
+	rand.Read(plain)
+	data := sealTest(t, _kdfKey, _testKey, plain)
+	header := len(data) - 3*(_chunkSize+16) - (100 + 16)
+	chunk := func(i int) []byte {
+		start := header + i*(_chunkSize+16)
+		return data[start : start+_chunkSize+16]
+	}
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"truncated at chunk boundary", data[:header+2*(_chunkSize+16)]},
+		{"truncated mid chunk", data[:len(data)-10]},
+		{"last chunk dropped", data[:header+3*(_chunkSize+16)]},
+		{"appended", append(bytes.Clone(data), 0)},
+		{"reordered", slices.Concat(data[:header], chunk(1), chunk(0), data[header+2*(_chunkSize+16):])},
+		{"flipped body bit", flipBit(data, header+_chunkSize/2)},
+		{"flipped tag bit", flipBit(data, len(data)-1)},
+		{"flipped salt bit", flipBit(data, 6)},
+	}
+	for _, tt := range tests {
+		if _, err := openStream(tt.data, _testKey); err == nil {
+			t.Errorf("%s: stream opened", tt.name)
+		}
+		if _, err := openRandom(tt.data, _testKey); err == nil {
+			t.Errorf("%s: random access opened", tt.name)
+		}
+	}
+}
+
+func TestContainerWrongSecret(t *testing.T) {
+	plain := []byte("attack at dawn")
+	otherKey := "ff" + _testKey[2:]
+
+	data := sealTest(t, _kdfKey, _testKey, plain)
+	if _, err := openStream(data, otherKey); !errors.Is(err, errOpenChunk) {
+		t.Errorf("wrong key: got %v, want %v", err, errOpenChunk)
+	}
+	if _, err := openRandom(data, otherKey); !errors.Is(err, errOpenChunk) {
+		t.Errorf("wrong key, random access: got %v, want %v", err, errOpenChunk)
+	}
+
+	data = sealTest(t, _kdfArgon2id, "correct horse", plain)
+	if _, err := openStream(data, "correct horsE"); !errors.Is(err, errOpenChunk) {
+		t.Errorf("wrong passphrase: got %v, want %v", err, errOpenChunk)
+	}
+}
+
+func TestContainerKeyMustBe32Bytes(t *testing.T) {
+	h, err := newContainerHeader(rand.Reader, _kdfKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"secret", _testKey[:32], _testKey + "00", "zz" + _testKey[2:]} {
+		if _, err := h.aead(key); !errors.Is(err, errAESKey) {
+			t.Errorf("aead(%q) = %v, want %v", key, err, errAESKey)
+		}
+	}
+}
+
+func TestReadContainerHeaderInvalid(t *testing.T) {
+	valid := containerHeader{
+		Version: 2, KDF: _kdfArgon2id, Time: 3, Memory: 64 * 1024, Threads: 4,
+		Salt: make([]byte, _encryptSalt), ChunkSize: _chunkSize, Nonce: make([]byte, _noncePrefix),
+	}
+	if _, err := readContainerHeader(bytes.NewReader(valid.marshal())); err != nil {
+		t.Fatalf("valid header: %v", err)
+	}
+
+	tests := map[string]func(h *containerHeader){
+		"version":    func(h *containerHeader) { h.Version = 9 },
+		"kdf":        func(h *containerHeader) { h.KDF = 7 },
+		"time":       func(h *containerHeader) { h.Time = 17 },
+		"memory":     func(h *containerHeader) { h.Memory = 5 * 1024 * 1024 },
+		"threads":    func(h *containerHeader) { h.Threads = 0 },
+		"chunk size": func(h *containerHeader) { h.ChunkSize = 1 << 30 },
+	}
+	for name, mutate := range tests {
+		h := valid
+		mutate(&h)
+		if _, err := readContainerHeader(bytes.NewReader(h.marshal())); err == nil {
+			t.Errorf("%s: invalid header accepted", name)
+		}
+	}
+
+	if _, err := readContainerHeader(bytes.NewReader([]byte("PK\x03\x04 not ours"))); !errors.Is(err, errNotContainer) {
+		t.Errorf("got %v, want %v", err, errNotContainer)
+	}
+}
+
+// The text was encrypted by the first version with passphrase support, whose
+// containers are a single GCM message.
+func TestDecryptV1Text(t *testing.T) {
+	const encrypted = "TUVJRQEBAAAAAwABAAAE3BNeH/o6jm7jlslrLQMKfspllXDdv15eE0Yf3fIDf7xCJ2ilNaBi0nk/pvb5L46lmBqrh6m49cFT"
+	data, ok := decodeContainerText(encrypted)
+	if !ok {
+		t.Fatal("v1 text not recognized")
+	}
+	got, err := decryptTextWithPassphrase(data, "v1 passphrase")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "hello from v1" {
+		t.Errorf("got %q, want %q", got, "hello from v1")
+	}
+
+	path := filepath.Join(t.TempDir(), "v1.txt.aes256")
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	r, size, closer, err := openContainerFile(path, func(byte) (string, error) { return "v1 passphrase", nil })
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closer.Close()
+	b, err := io.ReadAll(io.NewSectionReader(r, 0, size))
+	if err != nil || string(b) != "hello from v1" {
+		t.Errorf("openContainerFile = %q, %v", b, err)
+	}
+}
+
+// Files and texts of rice have no header, they must not be taken for
+// containers so that decrypt falls back to rice.
+func TestLegacyNotContainer(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "legacy.aes256")
+	legacy := make([]byte, 64)
+	rand.Read(legacy)
+	legacy[0] = 'X'
+	if err := os.WriteFile(path, legacy, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	ok, err := isContainerFile(path)
+	if err != nil || ok {
+		t.Errorf("isContainerFile = %v, %v, want false", ok, err)
+	}
+	if _, ok := decodeContainerText(base64.StdEncoding.EncodeToString(legacy)); ok {
+		t.Error("legacy text taken for a container")
+	}
+}
+
+func TestTextWithPassphrase(t *testing.T) {
+	s, err := encryptTextWithPassphrase("hello", "pass")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, ok := decodeContainerText(s)
+	if !ok {
+		t.Fatal("text not recognized")
+	}
+	if got, err := decryptTextWithPassphrase(data, "pass"); err != nil || got != "hello" {
+		t.Errorf("got %q, %v", got, err)
+	}
+}
+
+func flipBit(data []byte, i int) []byte {
+	b := bytes.Clone(data)
+	b[i] ^= 1
+	return b
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/chacha20"
+)
+
+// Argon2id parameters of derive, changing them changes every derived password.
+const (
+	_deriveTime    = 3
+	_deriveMemory  = 64 * 1024
+	_deriveThreads = 4
+	_deriveSalt    = "pwdgen/derive/v1"
+)
+
+// keystream is a deterministic reader of the ChaCha20 key stream.
+type keystream struct {
+	c *chacha20.Cipher
+}
+
+func (k *keystream) Read(p []byte) (int, error) {
+	clear(p)
+	k.c.XORKeyStream(p, p)
+	return len(p), nil
+}
+
+// deriveReader derives a byte stream from the master password, site, login
+// and counter with Argon2id. The same inputs always give the same stream.
+func deriveReader(master, site, login string, counter uint32) (*keystream, error) {
+	if master == "" {
+		return nil, errors.New("master password is empty")
+	}
+	site = strings.ToLower(strings.TrimSpace(site))
+	if site == "" {
+		return nil, errors.New("site is empty")
+	}
+
+	// Length prefixed fields, so that ("ab", "c") and ("a", "bc") differ.
+	salt := []byte(_deriveSalt)
+	for _, field := range []string{site, login} {
+		salt = binary.BigEndian.AppendUint32(salt, uint32(len(field)))
+		salt = append(salt, field...)
+	}
+	salt = binary.BigEndian.AppendUint32(salt, counter)
+
+	key := argon2.IDKey([]byte(master), salt, _deriveTime, _deriveMemory, _deriveThreads, chacha20.KeySize)
+	c, err := chacha20.NewUnauthenticatedCipher(key, make([]byte, chacha20.NonceSize))
+	if err != nil {
+		return nil, err
+	}
+	return &keystream{c: c}, nil
+}
+
+// derivePasswordV1 builds the password from the derived stream. It is a
+// frozen copy of the first Policy.Generate, which inserted every character at
+// a random position: derived passwords must never change, so it must not
+// follow later changes of Generate, and neither may randomIndex change. The
+// golden vectors in derive_test.go pin the output.
+func derivePasswordV1(reader io.Reader, p Policy) (string, error) {
+	if err := p.Validate(); err != nil {
+		return "", err
+	}
+
+	var (
+		result  []byte
+		classes = p.classes()
+		counts  = make([]int, len(classes))
+	)
+	insert := func(i int, ch byte) error {
+		counts[i]++
+		if len(result) == 0 {
+			result = append(result, ch)
+			return nil
+		}
+		k, err := randomIndex(reader, len(result)+1)
+		if err != nil {
+			return err
+		}
+		result = slices.Insert(result, k, ch)
+		return nil
+	}
+
+	for i, c := range classes {
+		for j := 0; j < c.Min; j++ {
+			k, err := randomIndex(reader, len(c.Chars))
+			if err != nil {
+				return "", err
+			}
+			if err := insert(i, c.Chars[k]); err != nil {
+				return "", err
+			}
+		}
+	}
+	for len(result) < p.Length {
+		total := 0
+		for i, c := range classes {
+			if c.Chars != "" && (c.Max < 0 || counts[i] < c.Max) {
+				total += len(c.Chars)
+			}
+		}
+		pick, err := randomIndex(reader, total)
+		if err != nil {
+			return "", err
+		}
+		for i, c := range classes {
+			if c.Chars == "" || (c.Max >= 0 && counts[i] >= c.Max) {
+				continue
+			}
+			if pick < len(c.Chars) {
+				if err := insert(i, c.Chars[pick]); err != nil {
+					return "", err
+				}
+				break
+			}
+			pick -= len(c.Chars)
+		}
+	}
+	return string(result), nil
+}
+
+func (m *PwdGenCLI) Derive(cmd *cobra.Command, args []string) error {
+	site, _ := cmd.Flags().GetString("site")
+	login, _ := cmd.Flags().GetString("login")
+	counter, _ := cmd.Flags().GetUint32("counter")
+	fromClipboard, _ := cmd.Flags().GetBool("clipboard")
+
+	policy, err := policyFromFlags(cmd)
+	if err != nil {
+		return err
+	}
+	master, err := readSecret("主密码: ", fromClipboard)
+	if err != nil {
+		return err
+	}
+	reader, err := deriveReader(master, site, login, counter)
+	if err != nil {
+		return err
+	}
+	s, err := derivePasswordV1(reader, policy)
+	if err != nil {
+		return fmt.Errorf("derive password err: %w", err)
+	}
+	return writeOutput(cmd, s)
+}
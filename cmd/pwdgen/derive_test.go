@@ -0,0 +1,38 @@
+package main
+
+import "testing"
+
+// Derived passwords must stay the same across versions, the vectors were
+// produced by the first release of derive.
+func TestDeriveGolden(t *testing.T) {
+	custom := newPolicy(3, 20)
+	custom.Digits.Min = 3
+	noAmbiguous := setLevel(4, 12).Policy()
+	noAmbiguous.NoAmbiguous = true
+	noAmbiguous.Exclude = "xyz"
+
+	tests := []struct {
+		site, login string
+		counter     uint32
+		policy      Policy
+		want        string
+	}{
+		{"example.com", "alice", 1, setLevel(4, 16).Policy(), `5OtYfO+"c>8-Qx"0`},
+		{"GitHub.com", "bob@example.com", 2, custom, "x6793IOen1ujff9SyPrc"},
+		{"bank.example", "", 1, noAmbiguous, `3Je8X%T~c"v}`},
+		{"short.io", "", 1, setLevel(1, 6).Policy(), "747359"},
+	}
+	for _, tt := range tests {
+		reader, err := deriveReader("correct horse battery staple", tt.site, tt.login, tt.counter)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := derivePasswordV1(reader, tt.policy)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Errorf("derive %s %s %d = %q, want %q", tt.site, tt.login, tt.counter, got, tt.want)
+		}
+	}
+}
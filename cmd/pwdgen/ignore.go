@@ -0,0 +1,168 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// _defaultIgnoreFile is read from the root of an encrypted folder if present.
+const _defaultIgnoreFile = ".meiignore"
+
+// ignoreRule is one line of a gitignore style file.
+type ignoreRule struct {
+	segments []string
+	negate   bool
+	dirOnly  bool
+}
+
+// ignoreMatcher decides which files of a folder are left out of the archive,
+// like gitignore the last matching rule wins.
+type ignoreMatcher struct {
+	rules []ignoreRule
+}
+
+// parseIgnoreRule parses a line, ok is false for blank lines and comments.
+//
+// A leading ! negates the rule and a trailing / matches directories only. A
+// pattern with a / anywhere but at the end is relative to the root, others
+// match at any depth. ** matches any number of directories.
+func parseIgnoreRule(line string) (rule ignoreRule, ok bool, err error) {
+	line = strings.TrimRight(line, " \t\r")
+	if line == "" || strings.HasPrefix(line, "#") {
+		return rule, false, nil
+	}
+	if strings.HasPrefix(line, "!") {
+		rule.negate = true
+		line = line[1:]
+	} else if strings.HasPrefix(line, `\!`) || strings.HasPrefix(line, `\#`) {
+		line = line[1:]
+	}
+	if strings.HasSuffix(line, "/") {
+		rule.dirOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+	if line == "" {
+		return rule, false, nil
+	}
+
+	anchored := strings.Contains(line, "/")
+	line = strings.TrimPrefix(line, "/")
+	if !anchored {
+		rule.segments = append(rule.segments, "**")
+	}
+	for _, seg := range strings.Split(line, "/") {
+		if seg == "" {
+			continue
+		}
+		if _, err := path.Match(seg, ""); err != nil {
+			return rule, false, fmt.Errorf("invalid pattern %q", line)
+		}
+		rule.segments = append(rule.segments, seg)
+	}
+	return rule, true, nil
+}
+
+// matchSegments matches the path segments against the pattern segments.
+func matchSegments(pattern, name []string) bool {
+	if len(pattern) == 0 {
+		return len(name) == 0
+	}
+	if pattern[0] == "**" {
+		// A trailing ** matches everything inside, but not the directory
+		// itself.
+		if len(pattern) == 1 {
+			return len(name) > 0
+		}
+		for i := 0; i <= len(name); i++ {
+			if matchSegments(pattern[1:], name[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(name) == 0 {
+		return false
+	}
+	if ok, _ := path.Match(pattern[0], name[0]); !ok {
+		return false
+	}
+	return matchSegments(pattern[1:], name[1:])
+}
+
+// Match reports whether the slash separated path relative to the root is
+// ignored. Contents of an ignored directory are not looked at.
+func (m *ignoreMatcher) Match(rel string, isDir bool) bool {
+	if m == nil {
+		return false
+	}
+	name := strings.Split(rel, "/")
+	ignored := false
+	for _, rule := range m.rules {
+		if rule.dirOnly && !isDir {
+			continue
+		}
+		if matchSegments(rule.segments, name) {
+			ignored = !rule.negate
+		}
+	}
+	return ignored
+}
+
+func (m *ignoreMatcher) add(lines []string, source string) error {
+	for i, line := range lines {
+		rule, ok, err := parseIgnoreRule(line)
+		if err != nil {
+			return fmt.Errorf("%s:%d: %w", source, i+1, err)
+		}
+		if ok {
+			m.rules = append(m.rules, rule)
+		}
+	}
+	return nil
+}
+
+func readIgnoreFile(name string) ([]string, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var lines []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	return lines, scanner.Err()
+}
+
+// newIgnoreMatcher loads .meiignore of the folder, then the ignore files and
+// at last the patterns, so the command line overrides the files.
+func newIgnoreMatcher(sourceDir string, files, patterns []string) (*ignoreMatcher, error) {
+	m := &ignoreMatcher{}
+	defaultFile := filepath.Join(sourceDir, _defaultIgnoreFile)
+	lines, err := readIgnoreFile(defaultFile)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	if err := m.add(lines, defaultFile); err != nil {
+		return nil, err
+	}
+	for _, file := range files {
+		lines, err := readIgnoreFile(file)
+		if err != nil {
+			return nil, err
+		}
+		if err := m.add(lines, file); err != nil {
+			return nil, err
+		}
+	}
+	if err := m.add(patterns, "--ignore"); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
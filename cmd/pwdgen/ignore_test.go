@@ -0,0 +1,67 @@
+package main
+
+import "testing"
+
+func TestIgnoreMatcher(t *testing.T) {
+	tests := []struct {
+		patterns []string
+		path     string
+		isDir    bool
+		want     bool
+	}{
+		// Unanchored patterns match at any depth.
+		{[]string{"*.log"}, "a.log", false, true},
+		{[]string{"*.log"}, "x/y/a.log", false, true},
+		{[]string{"*.log"}, "a.txt", false, false},
+
+		// A / other than a trailing one anchors to the root.
+		{[]string{"/build"}, "build", true, true},
+		{[]string{"/build"}, "src/build", true, false},
+		{[]string{"docs/*.md"}, "docs/a.md", false, true},
+		{[]string{"docs/*.md"}, "x/docs/a.md", false, false},
+		{[]string{"docs/*.md"}, "docs/sub/a.md", false, false},
+
+		// A trailing / matches directories only.
+		{[]string{"cache/"}, "cache", true, true},
+		{[]string{"cache/"}, "cache", false, false},
+		{[]string{"cache/"}, "x/cache", true, true},
+
+		// The last matching rule wins.
+		{[]string{"*.log", "!keep.log"}, "keep.log", false, false},
+		{[]string{"*.log", "!keep.log"}, "drop.log", false, true},
+		{[]string{"!keep.log", "*.log"}, "keep.log", false, true},
+		{[]string{`\!bang`}, "!bang", false, true},
+
+		// ** matches any number of directories.
+		{[]string{"**/node_modules"}, "node_modules", true, true},
+		{[]string{"**/node_modules"}, "a/b/node_modules", true, true},
+		{[]string{"a/**/b"}, "a/b", false, true},
+		{[]string{"a/**/b"}, "a/x/y/b", false, true},
+		{[]string{"a/**/b"}, "x/a/b", false, false},
+		{[]string{"logs/**"}, "logs/x/y.txt", false, true},
+		{[]string{"logs/**"}, "logs", true, false},
+
+		// Comments and blank lines are skipped.
+		{[]string{"# *.log", ""}, "a.log", false, false},
+	}
+	for _, tt := range tests {
+		m := &ignoreMatcher{}
+		if err := m.add(tt.patterns, "test"); err != nil {
+			t.Fatalf("%q: %v", tt.patterns, err)
+		}
+		if got := m.Match(tt.path, tt.isDir); got != tt.want {
+			t.Errorf("%q Match(%q, %v) = %v, want %v", tt.patterns, tt.path, tt.isDir, got, tt.want)
+		}
+	}
+
+	var nilMatcher *ignoreMatcher
+	if nilMatcher.Match("a", false) {
+		t.Error("nil matcher ignored a file")
+	}
+}
+
+func TestParseIgnoreRuleInvalid(t *testing.T) {
+	if _, _, err := parseIgnoreRule("a/[b"); err == nil {
+		t.Error("invalid pattern accepted")
+	}
+}
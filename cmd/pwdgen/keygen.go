@@ -0,0 +1,311 @@
+package main
+
+import (
+	"crypto"
+	"crypto/ecdh"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/crypto/ssh"
+)
+
+// keyTypes lists the key pairs supported by keygen.
+var keyTypes = []string{"ed25519", "rsa", "wireguard", "age"}
+
+// writeKeyFile writes the file with perm, an existing file is only replaced
+// when force is set.
+func writeKeyFile(path string, data []byte, perm os.FileMode, force bool) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	flag := os.O_WRONLY | os.O_CREATE | os.O_EXCL
+	if force {
+		flag = os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	}
+	f, err := os.OpenFile(path, flag, perm)
+	if err != nil {
+		if errors.Is(err, os.ErrExist) {
+			return fmt.Errorf("%s already exists, use --force to overwrite", path)
+		}
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	// OpenFile keeps the mode of a replaced file.
+	return os.Chmod(path, perm)
+}
+
+// sshKeyPair returns the OpenSSH private key and the authorized_keys line.
+func sshKeyPair(reader io.Reader, keyType string, bits int, comment string, passphrase string) ([]byte, []byte, ssh.PublicKey, error) {
+	var (
+		priv crypto.PrivateKey
+		pub  crypto.PublicKey
+	)
+	switch keyType {
+	case "ed25519":
+		p, k, err := ed25519.GenerateKey(reader)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		pub, priv = p, k
+	case "rsa":
+		if bits < 2048 || bits > 16384 {
+			return nil, nil, nil, errors.New("rsa bits must range 2048-16384")
+		}
+		k, err := rsa.GenerateKey(reader, bits)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		pub, priv = &k.PublicKey, k
+	default:
+		return nil, nil, nil, fmt.Errorf("unsupported ssh key type %s", keyType)
+	}
+
+	var (
+		block *pem.Block
+		err   error
+	)
+	if passphrase != "" {
+		block, err = ssh.MarshalPrivateKeyWithPassphrase(priv, comment, []byte(passphrase))
+	} else {
+		block, err = ssh.MarshalPrivateKey(priv, comment)
+	}
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	sshPub, err := ssh.NewPublicKey(pub)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	authorized := strings.TrimSuffix(string(ssh.MarshalAuthorizedKey(sshPub)), "\n")
+	if comment != "" {
+		authorized += " " + comment
+	}
+	return pem.EncodeToMemory(block), []byte(authorized + "\n"), sshPub, nil
+}
+
+// x25519KeyPair returns a X25519 private key and its public key. The private
+// key is clamped like wg genkey does, age clamps it on use anyway.
+func x25519KeyPair(reader io.Reader) (priv, pub []byte, err error) {
+	priv = make([]byte, 32)
+	if _, err := io.ReadFull(reader, priv); err != nil {
+		return nil, nil, err
+	}
+	priv[0] &= 248
+	priv[31] = priv[31]&127 | 64
+	k, err := ecdh.X25519().NewPrivateKey(priv)
+	if err != nil {
+		return nil, nil, err
+	}
+	return priv, k.PublicKey().Bytes(), nil
+}
+
+const bech32Charset = "qpzry9x8gf2tvdw0s3jn54khce6mua7l"
+
+func bech32Polymod(values []byte) uint32 {
+	gen := [5]uint32{0x3b6a57b2, 0x26508e6d, 0x1ea119fa, 0x3d4233dd, 0x2a1462b3}
+	chk := uint32(1)
+	for _, v := range values {
+		top := chk >> 25
+		chk = (chk&0x1ffffff)<<5 ^ uint32(v)
+		for i := 0; i < 5; i++ {
+			if (top>>i)&1 == 1 {
+				chk ^= gen[i]
+			}
+		}
+	}
+	return chk
+}
+
+// bech32Encode encodes data with the lowercase hrp as BIP 173 bech32, the
+// encoding of age keys.
+func bech32Encode(hrp string, data []byte) string {
+	// Regroup 8 bit bytes into 5 bit values.
+	var (
+		values []byte
+		acc    uint32
+		n      uint
+	)
+	for _, b := range data {
+		acc = acc<<8 | uint32(b)
+		n += 8
+		for n >= 5 {
+			n -= 5
+			values = append(values, byte(acc>>n)&31)
+		}
+	}
+	if n > 0 {
+		values = append(values, byte(acc<<(5-n))&31)
+	}
+
+	expanded := make([]byte, 0, len(hrp)*2+1+len(values)+6)
+	for i := 0; i < len(hrp); i++ {
+		expanded = append(expanded, hrp[i]>>5)
+	}
+	expanded = append(expanded, 0)
+	for i := 0; i < len(hrp); i++ {
+		expanded = append(expanded, hrp[i]&31)
+	}
+	expanded = append(expanded, values...)
+	polymod := bech32Polymod(append(expanded, 0, 0, 0, 0, 0, 0)) ^ 1
+
+	var b strings.Builder
+	b.WriteString(hrp)
+	b.WriteByte('1')
+	for _, v := range values {
+		b.WriteByte(bech32Charset[v])
+	}
+	for i := 0; i < 6; i++ {
+		b.WriteByte(bech32Charset[(polymod>>(5*(5-i)))&31])
+	}
+	return b.String()
+}
+
+// keyFilesFree returns an error if one of the paths exists, so a failure
+// cannot leave a private key without its public key.
+func keyFilesFree(paths ...string) error {
+	for _, path := range paths {
+		if _, err := os.Lstat(path); err == nil {
+			return fmt.Errorf("%s already exists, use --force to overwrite", path)
+		} else if !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *PwdGenCLI) Keygen(cmd *cobra.Command, args []string) error {
+	file, _ := cmd.Flags().GetString("file")
+	comment, _ := cmd.Flags().GetString("comment")
+	bits, _ := cmd.Flags().GetInt("bits")
+	askPassphrase, _ := cmd.Flags().GetBool("passphrase")
+	genPassphrase, _ := cmd.Flags().GetBool("gen-passphrase")
+	force, _ := cmd.Flags().GetBool("force")
+
+	keyType := "ed25519"
+	if len(args) > 0 {
+		keyType = strings.ToLower(args[0])
+	}
+	if file == "" {
+		switch keyType {
+		case "wireguard":
+			file = "wg.key"
+		case "age":
+			file = "age.key"
+		default:
+			file = "id_" + keyType
+		}
+	}
+	// --file is the private key for every type, the public key goes next to
+	// it with .pub appended.
+	pubFile := file + ".pub"
+
+	var (
+		priv, pub []byte
+		summary   string
+		secret    string
+	)
+	switch keyType {
+	case "ed25519", "rsa":
+		if comment == "" {
+			host, _ := os.Hostname()
+			name := os.Getenv("USER")
+			if u, err := user.Current(); err == nil {
+				name = u.Username
+			}
+			comment = name + "@" + host
+		}
+		if !force {
+			if err := keyFilesFree(file, pubFile); err != nil {
+				return err
+			}
+		}
+
+		switch {
+		case genPassphrase:
+			words, err := loadWordlist("")
+			if err != nil {
+				return err
+			}
+			if secret, _, err = passphrase(rand.Reader, words, passphraseConf{Words: 6, Separator: "-"}); err != nil {
+				return err
+			}
+		case askPassphrase:
+			var err error
+			if secret, err = readNewSecret("私钥密码: ", false); err != nil {
+				return err
+			}
+		}
+
+		var (
+			sshPub ssh.PublicKey
+			err    error
+		)
+		priv, pub, sshPub, err = sshKeyPair(rand.Reader, keyType, bits, comment, secret)
+		if err != nil {
+			return fmt.Errorf("generate %s key err: %w", keyType, err)
+		}
+		summary = fmt.Sprintf("私钥: %s\n公钥: %s\n指纹: %s %s\n", file, pubFile, ssh.FingerprintSHA256(sshPub), comment)
+
+	case "wireguard", "age":
+		if askPassphrase || genPassphrase {
+			return fmt.Errorf("%s keys do not support a passphrase", keyType)
+		}
+		if !force {
+			if err := keyFilesFree(file, pubFile); err != nil {
+				return err
+			}
+		}
+		privKey, pubKey, err := x25519KeyPair(rand.Reader)
+		if err != nil {
+			return fmt.Errorf("generate %s key err: %w", keyType, err)
+		}
+
+		var pubText string
+		if keyType == "wireguard" {
+			pubText = base64.StdEncoding.EncodeToString(pubKey)
+			priv = []byte(base64.StdEncoding.EncodeToString(privKey) + "\n")
+		} else {
+			pubText = bech32Encode("age", pubKey)
+			identity := strings.ToUpper(bech32Encode("age-secret-key-", privKey))
+			priv = []byte(fmt.Sprintf("# created: %s\n# public key: %s\n%s\n", time.Now().Format(time.RFC3339), pubText, identity))
+		}
+		pub = []byte(pubText + "\n")
+		summary = fmt.Sprintf("私钥: %s\n公钥: %s (%s)\n", file, pubText, pubFile)
+
+	default:
+		return fmt.Errorf("unsupported key type %s, want one of %s", keyType, strings.Join(keyTypes, ", "))
+	}
+
+	if err := writeKeyFile(file, priv, 0o600, force); err != nil {
+		return err
+	}
+	if err := writeKeyFile(pubFile, pub, 0o644, force); err != nil {
+		// Do not leave a private key behind that nothing points to.
+		os.Remove(file)
+		return err
+	}
+	fmt.Print(summary)
+	if genPassphrase {
+		fmt.Fprintln(os.Stderr, "私钥密码:")
+		return writeOutput(cmd, secret)
+	}
+	return nil
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+)
+
+// maskPosition is one position of a mask, literal positions are copied as
+// is, the others pick a random character of Chars.
+type maskPosition struct {
+	Chars   string
+	Literal bool
+}
+
+// Mask is a password template expanded position by position.
+//
+//	a A 9 ! *        lower, upper, digit, symbol, any of them
+//	?l ?u ?d ?s ?a   the same in hashcat style, ?h ?H for hex digits
+//	[a-f0-9]         custom character set, ranges are allowed
+//	\x ??            literal x and literal ?
+//
+// Every other character is a literal, e.g. "Aaaa-9999-!!!!".
+type Mask struct {
+	src       string
+	positions []maskPosition
+}
+
+// parseMask parses the mask, the symbol set and excluded characters of the
+// policy apply to every class and custom set.
+func parseMask(s string, p Policy) (Mask, error) {
+	exclude := p.Exclude
+	if p.NoAmbiguous {
+		exclude += Ambiguous
+	}
+	symbols := p.Symbols.Chars
+	if symbols == "" {
+		symbols = Symbols
+	}
+	all := LowerLetters + UpperLetters + Digits + symbols
+
+	m := Mask{src: s}
+	addSet := func(chars string) error {
+		var b strings.Builder
+		for _, r := range chars {
+			if r < '!' || r > '~' {
+				return fmt.Errorf("only printable ASCII characters are allowed in a set, got %q", r)
+			}
+			if !strings.ContainsRune(exclude, r) && !strings.ContainsRune(b.String(), r) {
+				b.WriteRune(r)
+			}
+		}
+		if b.Len() == 0 {
+			return fmt.Errorf("position %d: no characters left after exclusion", len(m.positions)+1)
+		}
+		m.positions = append(m.positions, maskPosition{Chars: b.String()})
+		return nil
+	}
+	addLiteral := func(r rune) {
+		m.positions = append(m.positions, maskPosition{Chars: string(r), Literal: true})
+	}
+
+	rs := []rune(s)
+	for i := 0; i < len(rs); i++ {
+		var err error
+		switch r := rs[i]; r {
+		case 'a':
+			err = addSet(LowerLetters)
+		case 'A':
+			err = addSet(UpperLetters)
+		case '9':
+			err = addSet(Digits)
+		case '!':
+			err = addSet(symbols)
+		case '*':
+			err = addSet(all)
+		case '\\':
+			if i+1 == len(rs) {
+				return m, errors.New("mask ends with an unfinished escape")
+			}
+			i++
+			addLiteral(rs[i])
+		case '?':
+			if i+1 == len(rs) {
+				return m, errors.New("mask ends with an unfinished ?")
+			}
+			i++
+			switch rs[i] {
+			case 'l':
+				err = addSet(LowerLetters)
+			case 'u':
+				err = addSet(UpperLetters)
+			case 'd':
+				err = addSet(Digits)
+			case 's':
+				err = addSet(symbols)
+			case 'a':
+				err = addSet(all)
+			case 'h':
+				err = addSet("0123456789abcdef")
+			case 'H':
+				err = addSet("0123456789ABCDEF")
+			case '?':
+				addLiteral('?')
+			default:
+				return m, fmt.Errorf("unknown mask class ?%c", rs[i])
+			}
+		case '[':
+			end := i + 1
+			for end < len(rs) && rs[end] != ']' {
+				if rs[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(rs) {
+				return m, errors.New("unclosed [ in mask")
+			}
+			set, e := expandSet(rs[i+1 : end])
+			if e != nil {
+				return m, e
+			}
+			err = addSet(set)
+			i = end
+		default:
+			addLiteral(r)
+		}
+		if err != nil {
+			return m, err
+		}
+	}
+
+	if len(m.positions) == 0 {
+		return m, errors.New("mask is empty")
+	} else if len(m.positions) > _maxLength {
+		return m, errors.New("mask too long")
+	}
+	return m, nil
+}
+
+// expandSet expands the ranges of a custom set, e.g. "a-f0-9_".
+func expandSet(rs []rune) (string, error) {
+	var b strings.Builder
+	for i := 0; i < len(rs); i++ {
+		r := rs[i]
+		if r == '\\' && i+1 < len(rs) {
+			i++
+			b.WriteRune(rs[i])
+			continue
+		}
+		if i+2 < len(rs) && rs[i+1] == '-' {
+			if rs[i+2] < r {
+				return "", fmt.Errorf("invalid range %c-%c in mask", r, rs[i+2])
+			}
+			for c := r; c <= rs[i+2]; c++ {
+				b.WriteRune(c)
+			}
+			i += 2
+			continue
+		}
+		b.WriteRune(r)
+	}
+	if b.Len() == 0 {
+		return "", errors.New("empty [] in mask")
+	}
+	return b.String(), nil
+}
+
+// Generate expands every position of the mask.
+func (m Mask) Generate(reader io.Reader) (string, error) {
+	var b strings.Builder
+	for _, p := range m.positions {
+		if p.Literal || len(p.Chars) == 1 {
+			b.WriteString(p.Chars)
+			continue
+		}
+		i, err := randomIndex(reader, len(p.Chars))
+		if err != nil {
+			return "", err
+		}
+		b.WriteByte(p.Chars[i])
+	}
+	return b.String(), nil
+}
+
+// Entropy returns the strength of a password generated by the mask in bits,
+// literals add nothing.
+func (m Mask) Entropy() float64 {
+	var bits float64
+	for _, p := range m.positions {
+		if !p.Literal {
+			bits += math.Log2(float64(len(p.Chars)))
+		}
+	}
+	return bits
+}
+
+func (m Mask) String() string {
+	return fmt.Sprintf("mask=%q", m.src)
+}
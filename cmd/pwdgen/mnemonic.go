@@ -0,0 +1,149 @@
+package main
+
+import (
+	"crypto/sha256"
+	_ "embed"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// bip39English is the BIP 39 English wordlist, 2048 words.
+// https://github.com/bitcoin/bips/blob/master/bip-0039/english.txt
+//
+//go:embed wordlist/bip39_english.txt
+var bip39English string
+
+var (
+	bip39Words = strings.Fields(bip39English)
+	// bip39Index maps a word and its first 4 letters, which are unique in
+	// the list, to its index.
+	bip39Index = func() map[string]int {
+		index := make(map[string]int, len(bip39Words)*2)
+		for i, w := range bip39Words {
+			index[w] = i
+			if len(w) > 4 {
+				index[w[:4]] = i
+			}
+		}
+		return index
+	}()
+)
+
+// keyToMnemonic encodes the key as BIP 39 words, the key length must be 16
+// to 32 bytes and a multiple of 4. A 32 byte key gives 24 words.
+func keyToMnemonic(key []byte) (string, error) {
+	if len(key) < 16 || len(key) > 32 || len(key)%4 != 0 {
+		return "", errors.New("key length must be 16, 20, 24, 28 or 32 bytes")
+	}
+	// The checksum is the first len/4 bits of the SHA-256 of the key.
+	checksumBits := uint(len(key) / 4)
+	sum := sha256.Sum256(key)
+	n := new(big.Int).SetBytes(key)
+	n.Lsh(n, checksumBits)
+	n.Or(n, big.NewInt(int64(sum[0]>>(8-checksumBits))))
+
+	words := make([]string, (len(key)*8+int(checksumBits))/11)
+	mask := big.NewInt(2047)
+	for i := len(words) - 1; i >= 0; i-- {
+		words[i] = bip39Words[new(big.Int).And(n, mask).Int64()]
+		n.Rsh(n, 11)
+	}
+	return strings.Join(words, " "), nil
+}
+
+// mnemonicToKey decodes the words of keyToMnemonic and checks the checksum.
+func mnemonicToKey(mnemonic string) ([]byte, error) {
+	words := strings.Fields(strings.ToLower(mnemonic))
+	if len(words) < 12 || len(words) > 24 || len(words)%3 != 0 {
+		return nil, errors.New("mnemonic must have 12, 15, 18, 21 or 24 words")
+	}
+	n := new(big.Int)
+	for _, w := range words {
+		i, ok := bip39Index[w]
+		if !ok {
+			return nil, fmt.Errorf("unknown word %q", w)
+		}
+		n.Lsh(n, 11)
+		n.Or(n, big.NewInt(int64(i)))
+	}
+
+	checksumBits := uint(len(words) / 3)
+	checksum := byte(new(big.Int).And(n, big.NewInt(1<<checksumBits-1)).Int64())
+	n.Rsh(n, checksumBits)
+	key := n.FillBytes(make([]byte, len(words)*4/3))
+	sum := sha256.Sum256(key)
+	if sum[0]>>(8-checksumBits) != checksum {
+		return nil, errors.New("mnemonic checksum mismatch, check for typos")
+	}
+	return key, nil
+}
+
+// isMnemonic reports whether s looks like a mnemonic rather than a key.
+func isMnemonic(s string) bool {
+	return len(strings.Fields(s)) >= 12
+}
+
+// aesKeyFromFlags returns the key given by --key, --mnemonic or MEI_AES_KEY.
+// A mnemonic is converted back into the hex key printed by encrypt --genkey.
+func aesKeyFromFlags(cmd *cobra.Command) (string, error) {
+	key, _ := cmd.Flags().GetString("key")
+	if ask, _ := cmd.Flags().GetBool("mnemonic"); ask {
+		var err error
+		if key, err = readSecret("助记词: ", false); err != nil {
+			return "", err
+		}
+	}
+	if key == "" {
+		k, ok := os.LookupEnv("MEI_AES_KEY")
+		if !ok {
+			return "", fmt.Errorf("env var MEI_AES_KEY not set")
+		}
+		key = k
+	}
+	if isMnemonic(key) {
+		b, err := mnemonicToKey(key)
+		if err != nil {
+			return "", fmt.Errorf("parse mnemonic err: %w", err)
+		}
+		key = hex.EncodeToString(b)
+	}
+	return key, nil
+}
+
+func (m *PwdGenCLI) MnemonicEncode(cmd *cobra.Command, args []string) error {
+	fromClipboard, _ := cmd.Flags().GetBool("clipboard")
+
+	s, err := readSecret("hex key: ", fromClipboard)
+	if err != nil {
+		return err
+	}
+	key, err := hex.DecodeString(strings.TrimSpace(s))
+	if err != nil {
+		return fmt.Errorf("decode hex key err: %w", err)
+	}
+	mnemonic, err := keyToMnemonic(key)
+	if err != nil {
+		return err
+	}
+	return writeOutput(cmd, mnemonic)
+}
+
+func (m *PwdGenCLI) MnemonicDecode(cmd *cobra.Command, args []string) error {
+	fromClipboard, _ := cmd.Flags().GetBool("clipboard")
+
+	s, err := readSecret("助记词: ", fromClipboard)
+	if err != nil {
+		return err
+	}
+	key, err := mnemonicToKey(s)
+	if err != nil {
+		return err
+	}
+	return writeOutput(cmd, hex.EncodeToString(key))
+}
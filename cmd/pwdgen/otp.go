@@ -0,0 +1,201 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pquerna/otp"
+	"github.com/pquerna/otp/hotp"
+	"github.com/pquerna/otp/totp"
+	"github.com/spf13/cobra"
+)
+
+// otpConf holds the parameters of a TOTP or HOTP code.
+type otpConf struct {
+	Secret    string
+	Algorithm otp.Algorithm
+	Digits    otp.Digits
+	Period    uint
+	HOTP      bool
+	Counter   uint64
+}
+
+func parseAlgorithm(s string) (otp.Algorithm, error) {
+	switch strings.ToLower(s) {
+	case "sha1":
+		return otp.AlgorithmSHA1, nil
+	case "sha256":
+		return otp.AlgorithmSHA256, nil
+	case "sha512":
+		return otp.AlgorithmSHA512, nil
+	default:
+		return 0, fmt.Errorf("unsupported algorithm %s, want sha1, sha256 or sha512", s)
+	}
+}
+
+func parseDigits(n int) (otp.Digits, error) {
+	switch n {
+	case 6:
+		return otp.DigitsSix, nil
+	case 8:
+		return otp.DigitsEight, nil
+	default:
+		return 0, fmt.Errorf("digits must be 6 or 8")
+	}
+}
+
+// otpConfFromFlags builds the conf from a base32 secret or an otpauth:// URI.
+// Parameters in the URI win unless the flag is set explicitly.
+func otpConfFromFlags(cmd *cobra.Command, input string) (otpConf, error) {
+	algorithm, _ := cmd.Flags().GetString("algorithm")
+	digits, _ := cmd.Flags().GetInt("digits")
+	period, _ := cmd.Flags().GetUint("period")
+	counter, _ := cmd.Flags().GetUint64("counter")
+
+	conf := otpConf{
+		Secret:  strings.ToUpper(strings.Join(strings.Fields(input), "")),
+		Period:  period,
+		HOTP:    cmd.Flags().Changed("counter"),
+		Counter: counter,
+	}
+	var err error
+	if conf.Algorithm, err = parseAlgorithm(algorithm); err != nil {
+		return conf, err
+	}
+	if conf.Digits, err = parseDigits(digits); err != nil {
+		return conf, err
+	}
+
+	if strings.HasPrefix(strings.ToLower(input), "otpauth://") {
+		key, err := otp.NewKeyFromURL(input)
+		if err != nil {
+			return conf, fmt.Errorf("parse otpauth uri err: %w", err)
+		}
+		conf.Secret = strings.ToUpper(key.Secret())
+		if !cmd.Flags().Changed("algorithm") {
+			conf.Algorithm = key.Algorithm()
+		}
+		if !cmd.Flags().Changed("digits") {
+			conf.Digits = key.Digits()
+		}
+		if !cmd.Flags().Changed("period") {
+			conf.Period = uint(key.Period())
+		}
+		if key.Type() == "hotp" && !conf.HOTP {
+			conf.HOTP = true
+			u, _ := url.Parse(key.URL())
+			conf.Counter, _ = strconv.ParseUint(u.Query().Get("counter"), 10, 64)
+		}
+	}
+
+	if conf.Secret == "" {
+		return conf, fmt.Errorf("secret is empty")
+	}
+	if conf.Period == 0 {
+		return conf, fmt.Errorf("period must > 0")
+	}
+	return conf, nil
+}
+
+// Code returns the code at t and how long it stays valid, the validity of a
+// HOTP code is 0.
+func (c otpConf) Code(t time.Time) (string, time.Duration, error) {
+	if c.HOTP {
+		code, err := hotp.GenerateCodeCustom(c.Secret, c.Counter, hotp.ValidateOpts{
+			Digits:    c.Digits,
+			Algorithm: c.Algorithm,
+		})
+		return code, 0, err
+	}
+	code, err := totp.GenerateCodeCustom(c.Secret, t, totp.ValidateOpts{
+		Period:    c.Period,
+		Digits:    c.Digits,
+		Algorithm: c.Algorithm,
+	})
+	period := int64(c.Period)
+	remaining := time.Duration(period-t.Unix()%period) * time.Second
+	return code, remaining, err
+}
+
+func (m *PwdGenCLI) OTP(cmd *cobra.Command, args []string) error {
+	var input string
+	if len(args) > 0 {
+		input = args[0]
+	} else {
+		var err error
+		if input, err = readSecret("secret 或 otpauth:// URI: ", false); err != nil {
+			return err
+		}
+	}
+
+	conf, err := otpConfFromFlags(cmd, input)
+	if err != nil {
+		return err
+	}
+	code, remaining, err := conf.Code(time.Now())
+	if err != nil {
+		return fmt.Errorf("generate code err: %w", err)
+	}
+	if err := writeOutput(cmd, code); err != nil {
+		return err
+	}
+	if conf.HOTP {
+		fmt.Fprintf(os.Stderr, "HOTP counter: %d\n", conf.Counter)
+	} else {
+		fmt.Fprintf(os.Stderr, "剩余有效期: %s\n", remaining)
+	}
+	return nil
+}
+
+func (m *PwdGenCLI) OTPNew(cmd *cobra.Command, args []string) error {
+	issuer, _ := cmd.Flags().GetString("issuer")
+	account, _ := cmd.Flags().GetString("account")
+	algorithm, _ := cmd.Flags().GetString("algorithm")
+	digits, _ := cmd.Flags().GetInt("digits")
+	period, _ := cmd.Flags().GetUint("period")
+	secretSize, _ := cmd.Flags().GetUint("secret-size")
+	isHOTP, _ := cmd.Flags().GetBool("hotp")
+	qrTarget, _ := cmd.Flags().GetString("qr")
+
+	alg, err := parseAlgorithm(algorithm)
+	if err != nil {
+		return err
+	}
+	d, err := parseDigits(digits)
+	if err != nil {
+		return err
+	}
+
+	var key *otp.Key
+	if isHOTP {
+		key, err = hotp.Generate(hotp.GenerateOpts{
+			Issuer:      issuer,
+			AccountName: account,
+			SecretSize:  secretSize,
+			Digits:      d,
+			Algorithm:   alg,
+		})
+	} else {
+		key, err = totp.Generate(totp.GenerateOpts{
+			Issuer:      issuer,
+			AccountName: account,
+			Period:      period,
+			SecretSize:  secretSize,
+			Digits:      d,
+			Algorithm:   alg,
+		})
+	}
+	if err != nil {
+		return fmt.Errorf("generate otp key err: %w", err)
+	}
+
+	fmt.Printf("secret: %s\nuri: %s\n", key.Secret(), key.URL())
+	if qrTarget != "" {
+		return writeQR(qrTarget, key.URL())
+	}
+	return nil
+}
@@ -0,0 +1,401 @@
+package main
+
+import (
+	"bufio"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+	"golang.org/x/crypto/pbkdf2"
+	"golang.org/x/crypto/scrypt"
+	"golang.org/x/term"
+)
+
+const (
+	// _maxHashMemory is the largest argon2id or scrypt memory in KiB, 4 GiB.
+	_maxHashMemory = 4 * 1024 * 1024
+
+	// Shorter salts and hashes are rejected, an empty hash would match every
+	// password.
+	_minSaltSize = 8
+	_minHashSize = 16
+	_maxHashSize = 1024
+)
+
+// checkHashSizes validates the salt and hash lengths in bytes.
+func checkHashSizes(saltSize, keySize int) error {
+	if saltSize < _minSaltSize || saltSize > _maxHashSize {
+		return fmt.Errorf("salt size must range %d-%d", _minSaltSize, _maxHashSize)
+	}
+	if keySize < _minHashSize || keySize > _maxHashSize {
+		return fmt.Errorf("key size must range %d-%d", _minHashSize, _maxHashSize)
+	}
+	return nil
+}
+
+// hashAlgorithms lists the algorithms supported by hash and verify.
+var hashAlgorithms = []string{"argon2id", "bcrypt", "scrypt", "pbkdf2-sha256", "pbkdf2-sha512"}
+
+// hashConf holds the parameters of a password hash, zero values are replaced
+// by the recommended ones, see withDefaults.
+type hashConf struct {
+	Algorithm string
+	// Cost is the bcrypt cost.
+	Cost int
+	// Time and Memory (KiB) are the argon2id iterations and memory.
+	Time   uint32
+	Memory uint32
+	// Parallelism is the argon2id threads or the scrypt p.
+	Parallelism int
+	// LogN and BlockSize are the scrypt log2(N) and r.
+	LogN      int
+	BlockSize int
+	// Iterations is the PBKDF2 iteration count.
+	Iterations int
+	SaltSize   int
+	KeySize    int
+}
+
+// withDefaults fills the zero parameters with the OWASP recommendations.
+func (c hashConf) withDefaults() hashConf {
+	setDefault := func(v *int, def int) {
+		if *v == 0 {
+			*v = def
+		}
+	}
+	setDefault(&c.SaltSize, 16)
+	setDefault(&c.KeySize, 32)
+	switch c.Algorithm {
+	case "bcrypt":
+		setDefault(&c.Cost, bcrypt.DefaultCost)
+	case "argon2id":
+		if c.Time == 0 {
+			c.Time = 3
+		}
+		if c.Memory == 0 {
+			c.Memory = 64 * 1024
+		}
+		setDefault(&c.Parallelism, 4)
+	case "scrypt":
+		setDefault(&c.LogN, 15)
+		setDefault(&c.BlockSize, 8)
+		setDefault(&c.Parallelism, 1)
+	case "pbkdf2-sha256":
+		setDefault(&c.Iterations, 600000)
+	case "pbkdf2-sha512":
+		setDefault(&c.Iterations, 210000)
+	}
+	return c
+}
+
+// Flags returns the command line flags that reproduce the parameters.
+func (c hashConf) Flags() string {
+	switch c.Algorithm {
+	case "bcrypt":
+		return fmt.Sprintf("-a bcrypt --cost %d", c.Cost)
+	case "argon2id":
+		return fmt.Sprintf("-a argon2id --time %d --memory %d --parallelism %d", c.Time, c.Memory, c.Parallelism)
+	case "scrypt":
+		return fmt.Sprintf("-a scrypt --ln %d --block-size %d --parallelism %d", c.LogN, c.BlockSize, c.Parallelism)
+	default:
+		return fmt.Sprintf("-a %s --iterations %d", c.Algorithm, c.Iterations)
+	}
+}
+
+func pbkdf2Hash(algorithm string) func() hash.Hash {
+	if algorithm == "pbkdf2-sha512" {
+		return sha512.New
+	}
+	return sha256.New
+}
+
+// deriveHash runs the algorithm of conf, bcrypt is not handled here since it
+// has its own encoding.
+func deriveHash(password string, salt []byte, conf hashConf) ([]byte, error) {
+	if err := checkHashSizes(len(salt), conf.KeySize); err != nil {
+		return nil, err
+	}
+	switch conf.Algorithm {
+	case "argon2id":
+		if conf.Parallelism < 1 || conf.Parallelism > 255 {
+			return nil, errors.New("parallelism must range 1-255")
+		}
+		if conf.Time < 1 {
+			return nil, errors.New("time must >= 1")
+		}
+		if conf.Memory < 8*uint32(conf.Parallelism) || conf.Memory > _maxHashMemory {
+			return nil, fmt.Errorf("memory must range 8*parallelism-%d KiB", _maxHashMemory)
+		}
+		return argon2.IDKey([]byte(password), salt, conf.Time, conf.Memory, uint8(conf.Parallelism), uint32(conf.KeySize)), nil
+	case "scrypt":
+		if conf.LogN < 1 || conf.LogN > 30 {
+			return nil, errors.New("ln must range 1-30")
+		}
+		if conf.BlockSize < 1 || conf.Parallelism < 1 || conf.Parallelism > 255 {
+			return nil, errors.New("block size must >= 1 and parallelism must range 1-255")
+		}
+		// scrypt needs 128*N*r bytes.
+		if uint64(128)<<conf.LogN*uint64(conf.BlockSize)/1024 > _maxHashMemory {
+			return nil, fmt.Errorf("memory 128*2^ln*r must not exceed %d KiB", _maxHashMemory)
+		}
+		return scrypt.Key([]byte(password), salt, 1<<conf.LogN, conf.BlockSize, conf.Parallelism, conf.KeySize)
+	case "pbkdf2-sha256", "pbkdf2-sha512":
+		if conf.Iterations < 1 {
+			return nil, errors.New("iterations must >= 1")
+		}
+		return pbkdf2.Key([]byte(password), salt, conf.Iterations, conf.KeySize, pbkdf2Hash(conf.Algorithm)), nil
+	default:
+		return nil, fmt.Errorf("unsupported algorithm %s, want one of %s", conf.Algorithm, strings.Join(hashAlgorithms, ", "))
+	}
+}
+
+// hashPassword hashes the password into a PHC string, e.g.
+// $argon2id$v=19$m=65536,t=3,p=4$salt$hash. bcrypt uses its own $2a$ format.
+func hashPassword(reader io.Reader, password string, conf hashConf) (string, error) {
+	conf = conf.withDefaults()
+	if conf.Algorithm == "bcrypt" {
+		b, err := bcrypt.GenerateFromPassword([]byte(password), conf.Cost)
+		return string(b), err
+	}
+
+	if err := checkHashSizes(conf.SaltSize, conf.KeySize); err != nil {
+		return "", err
+	}
+	salt := make([]byte, conf.SaltSize)
+	if _, err := io.ReadFull(reader, salt); err != nil {
+		return "", err
+	}
+	key, err := deriveHash(password, salt, conf)
+	if err != nil {
+		return "", err
+	}
+
+	var params string
+	switch conf.Algorithm {
+	case "argon2id":
+		params = fmt.Sprintf("v=%d$m=%d,t=%d,p=%d", argon2.Version, conf.Memory, conf.Time, conf.Parallelism)
+	case "scrypt":
+		params = fmt.Sprintf("ln=%d,r=%d,p=%d", conf.LogN, conf.BlockSize, conf.Parallelism)
+	default:
+		params = fmt.Sprintf("i=%d", conf.Iterations)
+	}
+	return fmt.Sprintf("$%s$%s$%s$%s", conf.Algorithm, params,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+// parsePHC parses a PHC string produced by hashPassword, it returns the
+// conf, salt and hash.
+func parsePHC(encoded string) (hashConf, []byte, []byte, error) {
+	var conf hashConf
+	parts := strings.Split(encoded, "$")
+	if len(parts) < 5 || parts[0] != "" {
+		return conf, nil, nil, errors.New("invalid phc string")
+	}
+	conf.Algorithm = parts[1]
+	parts = parts[2:]
+	if strings.HasPrefix(parts[0], "v=") {
+		if conf.Algorithm == "argon2id" && parts[0] != fmt.Sprintf("v=%d", argon2.Version) {
+			return conf, nil, nil, fmt.Errorf("unsupported argon2 version %s", parts[0])
+		}
+		parts = parts[1:]
+	}
+	if len(parts) != 3 {
+		return conf, nil, nil, errors.New("invalid phc string")
+	}
+
+	params := make(map[string]int)
+	for _, kv := range strings.Split(parts[0], ",") {
+		k, v, ok := strings.Cut(kv, "=")
+		n, err := strconv.Atoi(v)
+		if !ok || err != nil || n <= 0 {
+			return conf, nil, nil, fmt.Errorf("invalid phc parameter %q", kv)
+		}
+		params[k] = n
+	}
+	switch conf.Algorithm {
+	case "argon2id":
+		if params["m"] > _maxHashMemory || params["t"] > math.MaxUint32 {
+			return conf, nil, nil, errors.New("invalid phc string, argon2id parameters out of range")
+		}
+		conf.Memory, conf.Time, conf.Parallelism = uint32(params["m"]), uint32(params["t"]), params["p"]
+	case "scrypt":
+		conf.LogN, conf.BlockSize, conf.Parallelism = params["ln"], params["r"], params["p"]
+	case "pbkdf2-sha256", "pbkdf2-sha512":
+		conf.Iterations = params["i"]
+	default:
+		return conf, nil, nil, fmt.Errorf("unsupported algorithm %s", conf.Algorithm)
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[1])
+	if err != nil {
+		return conf, nil, nil, fmt.Errorf("decode salt err: %w", err)
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[2])
+	if err != nil {
+		return conf, nil, nil, fmt.Errorf("decode hash err: %w", err)
+	}
+	conf.SaltSize, conf.KeySize = len(salt), len(key)
+	if err := checkHashSizes(conf.SaltSize, conf.KeySize); err != nil {
+		return conf, nil, nil, fmt.Errorf("invalid phc string, %w", err)
+	}
+	return conf, salt, key, nil
+}
+
+// verifyPassword reports whether the password matches the encoded hash.
+func verifyPassword(password, encoded string) (bool, error) {
+	if strings.HasPrefix(encoded, "$2a$") || strings.HasPrefix(encoded, "$2b$") || strings.HasPrefix(encoded, "$2y$") {
+		err := bcrypt.CompareHashAndPassword([]byte(encoded), []byte(password))
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			return false, nil
+		}
+		return err == nil, err
+	}
+
+	conf, salt, key, err := parsePHC(encoded)
+	if err != nil {
+		return false, err
+	}
+	got, err := deriveHash(password, salt, conf)
+	if err != nil {
+		return false, err
+	}
+	return subtle.ConstantTimeCompare(got, key) == 1, nil
+}
+
+// benchmarkHash raises the main cost of conf until one hash takes at least
+// target, it returns the conf and the duration of its last hash.
+func benchmarkHash(conf hashConf, target time.Duration) (hashConf, time.Duration, error) {
+	conf = conf.withDefaults()
+	for {
+		start := time.Now()
+		if _, err := hashPassword(rand.Reader, "pwdgen-benchmark", conf); err != nil {
+			return conf, 0, err
+		}
+		elapsed := time.Since(start)
+		if elapsed >= target {
+			return conf, elapsed, nil
+		}
+
+		switch conf.Algorithm {
+		case "bcrypt":
+			if conf.Cost >= bcrypt.MaxCost {
+				return conf, elapsed, nil
+			}
+			conf.Cost++
+		case "argon2id":
+			conf.Time++
+		case "scrypt":
+			if conf.LogN >= 24 {
+				return conf, elapsed, nil
+			}
+			conf.LogN++
+		default:
+			// PBKDF2 is linear in the iterations, jump close to the target.
+			next := int(float64(conf.Iterations) * float64(target) / float64(max(elapsed, time.Millisecond)) * 1.05)
+			conf.Iterations = max(next, conf.Iterations+1)
+		}
+	}
+}
+
+func hashConfFromFlags(cmd *cobra.Command) hashConf {
+	var conf hashConf
+	conf.Algorithm, _ = cmd.Flags().GetString("algorithm")
+	conf.Cost, _ = cmd.Flags().GetInt("cost")
+	conf.Time, _ = cmd.Flags().GetUint32("time")
+	conf.Memory, _ = cmd.Flags().GetUint32("memory")
+	conf.Parallelism, _ = cmd.Flags().GetInt("parallelism")
+	conf.LogN, _ = cmd.Flags().GetInt("ln")
+	conf.BlockSize, _ = cmd.Flags().GetInt("block-size")
+	conf.Iterations, _ = cmd.Flags().GetInt("iterations")
+	conf.SaltSize, _ = cmd.Flags().GetInt("salt-size")
+	conf.KeySize, _ = cmd.Flags().GetInt("key-size")
+	conf.Algorithm = strings.ToLower(conf.Algorithm)
+	if conf.Algorithm == "pbkdf2" {
+		conf.Algorithm = "pbkdf2-sha256"
+	}
+	return conf
+}
+
+func (m *PwdGenCLI) Hash(cmd *cobra.Command, args []string) error {
+	fromClipboard, _ := cmd.Flags().GetBool("clipboard")
+	benchmark, _ := cmd.Flags().GetDuration("benchmark")
+	withPassword, _ := cmd.Flags().GetBool("with-password")
+	conf := hashConfFromFlags(cmd)
+
+	if benchmark > 0 {
+		tuned, elapsed, err := benchmarkHash(conf, benchmark)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%s\n", tuned.Flags())
+		fmt.Fprintf(os.Stderr, "耗时: %s\n", elapsed.Round(time.Millisecond))
+		return nil
+	}
+
+	// Piped stdin is hashed line by line, e.g. pwdgen --count 100 | pwdgen hash.
+	if !fromClipboard && !term.IsTerminal(int(os.Stdin.Fd())) {
+		scanner := bufio.NewScanner(stdinReader)
+		for scanner.Scan() {
+			password := strings.TrimRight(scanner.Text(), "\r")
+			if password == "" {
+				continue
+			}
+			s, err := hashPassword(rand.Reader, password, conf)
+			if err != nil {
+				return err
+			}
+			if withPassword {
+				fmt.Printf("%s\t%s\n", password, s)
+			} else {
+				fmt.Println(s)
+			}
+		}
+		return scanner.Err()
+	}
+
+	password, err := readSecret("密码: ", fromClipboard)
+	if err != nil {
+		return err
+	}
+	if password == "" {
+		return fmt.Errorf("password is empty")
+	}
+	s, err := hashPassword(rand.Reader, password, conf)
+	if err != nil {
+		return err
+	}
+	fmt.Println(s)
+	return nil
+}
+
+func (m *PwdGenCLI) Verify(cmd *cobra.Command, args []string) error {
+	fromClipboard, _ := cmd.Flags().GetBool("clipboard")
+
+	password, err := readSecret("密码: ", fromClipboard)
+	if err != nil {
+		return err
+	}
+	ok, err := verifyPassword(password, args[0])
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return errors.New("password does not match")
+	}
+	fmt.Println("密码正确")
+	return nil
+}
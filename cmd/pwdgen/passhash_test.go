@@ -0,0 +1,67 @@
+package main
+
+import (
+	"crypto/rand"
+	"testing"
+)
+
+func TestHashRoundTrip(t *testing.T) {
+	confs := []hashConf{
+		{Algorithm: "argon2id", Time: 1, Memory: 64, Parallelism: 1},
+		{Algorithm: "bcrypt", Cost: 4},
+		{Algorithm: "scrypt", LogN: 4},
+		{Algorithm: "pbkdf2-sha256", Iterations: 10},
+		{Algorithm: "pbkdf2-sha512", Iterations: 10},
+	}
+	for _, conf := range confs {
+		encoded, err := hashPassword(rand.Reader, "hunter2", conf)
+		if err != nil {
+			t.Fatalf("%s: %v", conf.Algorithm, err)
+		}
+		if ok, err := verifyPassword("hunter2", encoded); err != nil || !ok {
+			t.Errorf("%s: right password = %v, %v", conf.Algorithm, ok, err)
+		}
+		if ok, err := verifyPassword("hunter3", encoded); err != nil || ok {
+			t.Errorf("%s: wrong password = %v, %v", conf.Algorithm, ok, err)
+		}
+	}
+}
+
+func TestVerifyRejectsInvalidHash(t *testing.T) {
+	for _, encoded := range []string{
+		// Empty hashes would match every password.
+		"$argon2id$v=19$m=64,t=1,p=1$c2FsdHNhbHQ$",
+		"$pbkdf2-sha256$i=1$c2FsdHNhbHQ$",
+		"$scrypt$ln=4,r=8,p=1$c2FsdHNhbHQ$",
+		// Too short salt or hash.
+		"$pbkdf2-sha256$i=1$c2FsdA$c2FsdHNhbHRzYWx0c2FsdA",
+		"$pbkdf2-sha256$i=1$c2FsdHNhbHQ$c2FsdA",
+		// Parameters out of range.
+		"$argon2id$v=19$m=5000000,t=1,p=1$c2FsdHNhbHQ$c2FsdHNhbHRzYWx0c2FsdA",
+		"$argon2id$v=19$m=64,t=1,p=300$c2FsdHNhbHQ$c2FsdHNhbHRzYWx0c2FsdA",
+		"$scrypt$ln=30,r=8,p=1$c2FsdHNhbHQ$c2FsdHNhbHRzYWx0c2FsdA",
+		"$scrypt$ln=31,r=1,p=1$c2FsdHNhbHQ$c2FsdHNhbHRzYWx0c2FsdA",
+		"$argon2id$v=16$m=64,t=1,p=1$c2FsdHNhbHQ$c2FsdHNhbHRzYWx0c2FsdA",
+		"$md5$i=1$c2FsdHNhbHQ$c2FsdHNhbHRzYWx0c2FsdA",
+	} {
+		if ok, err := verifyPassword("anything", encoded); err == nil || ok {
+			t.Errorf("%s: got %v, %v, want an error", encoded, ok, err)
+		}
+	}
+}
+
+func TestHashRejectsInvalidConf(t *testing.T) {
+	for _, conf := range []hashConf{
+		{Algorithm: "pbkdf2-sha256", Iterations: 1, SaltSize: -1},
+		{Algorithm: "pbkdf2-sha256", Iterations: 1, SaltSize: 4},
+		{Algorithm: "pbkdf2-sha256", Iterations: 1, KeySize: -1},
+		{Algorithm: "pbkdf2-sha256", Iterations: 1, KeySize: 8},
+		{Algorithm: "argon2id", Time: 1, Memory: _maxHashMemory + 1, Parallelism: 1},
+		{Algorithm: "scrypt", LogN: 25, BlockSize: 8},
+		{Algorithm: "scrypt", LogN: 4, Parallelism: 1000},
+	} {
+		if _, err := hashPassword(rand.Reader, "hunter2", conf); err == nil {
+			t.Errorf("%+v: no error", conf)
+		}
+	}
+}
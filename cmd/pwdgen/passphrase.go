@@ -0,0 +1,152 @@
+package main
+
+import (
+	"bufio"
+	"crypto/rand"
+	_ "embed"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strings"
+	"unicode"
+
+	"github.com/spf13/cobra"
+)
+
+// effLargeWordlist is the EFF large wordlist for diceware, 7776 words.
+// https://www.eff.org/files/2016/07/18/eff_large_wordlist.txt
+//
+//go:embed wordlist/eff_large_wordlist.txt
+var effLargeWordlist string
+
+type passphraseConf struct {
+	Words      int
+	Separator  string
+	Capitalize bool
+	Digit      bool
+	Symbol     bool
+}
+
+// parseWordlist parses one word per line, diceware lines like "11111	abacus"
+// are supported as well. Empty lines, comments and duplicates are skipped.
+func parseWordlist(r io.Reader) ([]string, error) {
+	var (
+		words   []string
+		seen    = make(map[string]struct{})
+		scanner = bufio.NewScanner(r)
+	)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		word := fields[0]
+		if len(fields) > 1 && strings.Trim(fields[0], "0123456789") == "" {
+			word = fields[1]
+		}
+		if _, ok := seen[word]; ok {
+			continue
+		}
+		seen[word] = struct{}{}
+		words = append(words, word)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(words) < 2 {
+		return nil, errors.New("wordlist must contain at least 2 distinct words")
+	}
+	return words, nil
+}
+
+// loadWordlist loads the wordlist from file, the embedded EFF list is used
+// when path is empty.
+func loadWordlist(path string) ([]string, error) {
+	if path == "" {
+		return parseWordlist(strings.NewReader(effLargeWordlist))
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return parseWordlist(f)
+}
+
+// passphrase picks conf.Words random words from the list and returns the
+// passphrase together with its entropy in bits.
+func passphrase(reader io.Reader, words []string, conf passphraseConf) (string, float64, error) {
+	if conf.Words < 1 || conf.Words > 64 {
+		return "", 0, errors.New("words must range 1-64")
+	}
+
+	picked := make([]string, conf.Words)
+	for i := range picked {
+		n, err := randomIndex(reader, len(words))
+		if err != nil {
+			return "", 0, err
+		}
+		picked[i] = words[n]
+		if conf.Capitalize {
+			r := []rune(picked[i])
+			r[0] = unicode.ToUpper(r[0])
+			picked[i] = string(r)
+		}
+	}
+	entropy := float64(conf.Words) * math.Log2(float64(len(words)))
+
+	// The digit and symbol are appended to a random word.
+	appendRandom := func(chars string) error {
+		ch, err := randomElement(reader, chars)
+		if err != nil {
+			return err
+		}
+		n, err := randomIndex(reader, len(picked))
+		if err != nil {
+			return err
+		}
+		picked[n] += ch
+		entropy += math.Log2(float64(len(chars)))
+		return nil
+	}
+	if conf.Digit {
+		if err := appendRandom(Digits); err != nil {
+			return "", 0, err
+		}
+	}
+	if conf.Symbol {
+		if err := appendRandom(Symbols); err != nil {
+			return "", 0, err
+		}
+	}
+
+	return strings.Join(picked, conf.Separator), entropy, nil
+}
+
+func (m *PwdGenCLI) Passphrase(cmd *cobra.Command, args []string) error {
+	wordlistFile, _ := cmd.Flags().GetString("wordlist")
+
+	var conf passphraseConf
+	conf.Words, _ = cmd.Flags().GetInt("words")
+	conf.Separator, _ = cmd.Flags().GetString("separator")
+	conf.Capitalize, _ = cmd.Flags().GetBool("capitalize")
+	conf.Digit, _ = cmd.Flags().GetBool("digit")
+	conf.Symbol, _ = cmd.Flags().GetBool("symbol")
+
+	words, err := loadWordlist(wordlistFile)
+	if err != nil {
+		return fmt.Errorf("load wordlist err: %w", err)
+	}
+	s, entropy, err := passphrase(rand.Reader, words, conf)
+	if err != nil {
+		return err
+	}
+	if err := writeOutput(cmd, s); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "词表大小: %d, 熵: %.1f bits\n", len(words), entropy)
+	return nil
+}
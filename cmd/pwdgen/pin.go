@@ -0,0 +1,224 @@
+package main
+
+import (
+	"bufio"
+	"crypto/rand"
+	_ "embed"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+//go:embed wordlist/common_pins.txt
+var commonPinsText string
+
+// commonPins is the set of frequently chosen PINs.
+var commonPins = func() map[string]struct{} {
+	pins := make(map[string]struct{})
+	words, _ := parseWordlist(strings.NewReader(commonPinsText))
+	for _, w := range words {
+		pins[w] = struct{}{}
+	}
+	return pins
+}()
+
+const (
+	_minPinLength = 4
+	_maxPinLength = 12
+
+	// _pinSamples is the number of random PINs used to estimate the search
+	// space of PINs longer than those in _safePins.
+	_pinSamples = 200000
+)
+
+// weakPin returns why the PIN is weak, or "" if it is not.
+func weakPin(pin string) string {
+	if _, ok := commonPins[pin]; ok {
+		return "常见 PIN"
+	}
+
+	d := []byte(pin)
+	for i := 0; i+2 < len(d); i++ {
+		if d[i] == d[i+1] && d[i+1] == d[i+2] {
+			return "连续重复数字"
+		}
+		if step := int(d[i+1]) - int(d[i]); (step == 1 || step == -1) && int(d[i+2])-int(d[i+1]) == step {
+			return "连续递增或递减数字"
+		}
+	}
+
+	// Repeated blocks like 1212 or 123123.
+	for p := 1; p <= len(d)/2; p++ {
+		if len(d)%p == 0 && strings.Repeat(pin[:p], len(d)/p) == pin {
+			return "重复的数字组合"
+		}
+	}
+
+	var seen [10]bool
+	distinct := 0
+	for _, c := range d {
+		if !seen[c-'0'] {
+			seen[c-'0'] = true
+			distinct++
+		}
+	}
+	if distinct < (len(d)+1)/2 {
+		return "不同的数字太少"
+	}
+
+	if pinIsDate(pin) {
+		return "像日期或年份"
+	}
+	return ""
+}
+
+// pinIsDate reports whether the whole PIN reads as a year, MMDD, DDMM,
+// YYMMDD, MMDDYY, DDMMYY, YYYYMMDD, MMDDYYYY or DDMMYYYY.
+func pinIsDate(pin string) bool {
+	num := func(s string) int {
+		n, _ := strconv.Atoi(s)
+		return n
+	}
+	switch len(pin) {
+	case 4:
+		return isYear(pin) ||
+			validDate(2000, num(pin[:2]), num(pin[2:])) ||
+			validDate(2000, num(pin[2:]), num(pin[:2]))
+	case 6:
+		return validDate(2000+num(pin[:2]), num(pin[2:4]), num(pin[4:])) ||
+			validDate(2000+num(pin[4:]), num(pin[:2]), num(pin[2:4])) ||
+			validDate(2000+num(pin[4:]), num(pin[2:4]), num(pin[:2]))
+	case 8:
+		return isYear(pin[:4]) && validDate(num(pin[:4]), num(pin[4:6]), num(pin[6:])) ||
+			isYear(pin[4:]) && validDate(num(pin[4:]), num(pin[:2]), num(pin[2:4])) ||
+			isYear(pin[4:]) && validDate(num(pin[4:]), num(pin[2:4]), num(pin[:2]))
+	}
+	return false
+}
+
+func validDate(year, month, day int) bool {
+	if month < 1 || month > 12 || day < 1 {
+		return false
+	}
+	t := time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.UTC)
+	return t.Month() == time.Month(month) && t.Day() == day
+}
+
+// randomPin draws random PINs until one is not weak, so every safe PIN is
+// equally likely.
+func randomPin(reader io.Reader, length int) (string, error) {
+	if length < _minPinLength || length > _maxPinLength {
+		return "", fmt.Errorf("length must range %d-%d", _minPinLength, _maxPinLength)
+	}
+	b := make([]byte, length)
+	for {
+		for i := range b {
+			n, err := randomIndex(reader, len(Digits))
+			if err != nil {
+				return "", err
+			}
+			b[i] = Digits[n]
+		}
+		if weakPin(string(b)) == "" {
+			return string(b), nil
+		}
+	}
+}
+
+// _safePins is the number of PINs of length 4, 5 and 6 that are not weak,
+// counted with countSafePins. Update it when weakPin or common_pins.txt
+// changes, TestSafePins fails otherwise.
+var _safePins = map[int]float64{
+	4: 8691,
+	5: 92008,
+	6: 828652,
+}
+
+// countSafePins counts the PINs of the length that are not weak.
+func countSafePins(length int) int {
+	format := fmt.Sprintf("%%0%dd", length)
+	count := 0
+	for i := 0; i < int(math.Pow10(length)); i++ {
+		if weakPin(fmt.Sprintf(format, i)) == "" {
+			count++
+		}
+	}
+	return count
+}
+
+// pinSearchSpace returns how many PINs of the length are not weak. Lengths
+// in _safePins are exact, longer ones are estimated by sampling, exact tells
+// which.
+func pinSearchSpace(length int) (n float64, exact bool, err error) {
+	if n, ok := _safePins[length]; ok {
+		return n, true, nil
+	}
+
+	total := math.Pow10(length)
+	var (
+		reader   = bufio.NewReaderSize(rand.Reader, 4096)
+		b        = make([]byte, length)
+		accepted = 0
+	)
+	for i := 0; i < _pinSamples; i++ {
+		for j := range b {
+			k, err := randomIndex(reader, len(Digits))
+			if err != nil {
+				return 0, false, err
+			}
+			b[j] = Digits[k]
+		}
+		if weakPin(string(b)) == "" {
+			accepted++
+		}
+	}
+	return total * float64(accepted) / _pinSamples, false, nil
+}
+
+func (m *PwdGenCLI) Pin(cmd *cobra.Command, args []string) error {
+	length, _ := cmd.Flags().GetInt("length")
+	count, _ := cmd.Flags().GetInt("count")
+
+	if count < 1 || count > _maxCount {
+		return fmt.Errorf("count must range 1-%d", _maxCount)
+	}
+	if length < _minPinLength || length > _maxPinLength {
+		return fmt.Errorf("length must range %d-%d", _minPinLength, _maxPinLength)
+	}
+
+	if count == 1 {
+		s, err := randomPin(rand.Reader, length)
+		if err != nil {
+			return err
+		}
+		if err := writeOutput(cmd, s); err != nil {
+			return err
+		}
+	} else {
+		for i := 0; i < count; i++ {
+			s, err := randomPin(rand.Reader, length)
+			if err != nil {
+				return err
+			}
+			fmt.Println(s)
+		}
+	}
+
+	n, exact, err := pinSearchSpace(length)
+	if err != nil {
+		return err
+	}
+	approx := ""
+	if !exact {
+		approx = "约 "
+	}
+	fmt.Fprintf(os.Stderr, "可用组合: %s%.0f / %.0f (%.1f%%), 熵: %.1f bits\n",
+		approx, n, math.Pow10(length), n/math.Pow10(length)*100, math.Log2(n))
+	return nil
+}
@@ -0,0 +1,11 @@
+package main
+
+import "testing"
+
+func TestSafePins(t *testing.T) {
+	for length, want := range _safePins {
+		if got := countSafePins(length); float64(got) != want {
+			t.Errorf("countSafePins(%d) = %d, _safePins has %.0f", length, got, want)
+		}
+	}
+}
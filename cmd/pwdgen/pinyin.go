@@ -0,0 +1,146 @@
+package main
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strings"
+	"unicode"
+
+	"github.com/mozillazg/go-pinyin"
+	"github.com/spf13/cobra"
+)
+
+// commonHanzi 随机模式使用的常用字, 已去掉常见的多音字。
+const commonHanzi = "天人日月星山水火木金土风云雨雪花草树林森春夏秋冬东西南北上下左右前后小多高低短新" +
+	"旧黄蓝白黑紫青银一二三四五六七八九十百千年时秒早晚今明昨夕光影声香味心手口目耳身" +
+	"足家国城村门窗桌椅床灯书笔纸画字诗歌舞琴棋茶酒饭米面鱼肉鸡鸭牛羊马猪狗猫虫龙虎狮" +
+	"熊兔鹿江河湖海岛桥路船飞机电话网友爱情梦想美快笑哭走跳唱写听吃睡醒开关来去进出坐" +
+	"站雷霜冰雾波浪玉珠宝贝钱财福寿喜安康平静清亮暗冷热温暖甜苦辣酸咸软硬轻慢忙闲真对" +
+	"错反善方圆尖直弯深浅远近宽窄厚瘦老男女母兄弟姐妹儿孙爷奶叔姨学师班课本考试业问习" +
+	"练思知道理由因果始终初末外内里边园田松竹梅兰菊桃李杏梨枣橙瓜豆麦稻根枝芽苗籽烟尘" +
+	"灰池井泉溪湾港岸滩峰岭坡崖洞剑刀枪弓箭盾旗鼓钟铃镜烛伞帽裤鞋袜巾带针线布丝棉毛皮" +
+	"革"
+
+// _phraseAlphabet 估算自定义短语的熵时假设的字表大小, 约为常用字的数量。
+const _phraseAlphabet = 3500
+
+type pinyinConf struct {
+	Tone       bool
+	Capitalize bool
+	Separator  string
+}
+
+// distinctHanzi returns the characters of commonHanzi whose pinyin differs,
+// the first one of each syllable is kept. Sampling from them makes every
+// character add log2(len) bits to the romanised password.
+func distinctHanzi(conf pinyinConf) []rune {
+	var (
+		hanzi []rune
+		seen  = make(map[string]bool)
+	)
+	for _, r := range commonHanzi {
+		s, err := pinyinPassword(string(r), pinyinConf{Tone: conf.Tone})
+		if err != nil || seen[s] {
+			continue
+		}
+		seen[s] = true
+		hanzi = append(hanzi, r)
+	}
+	return hanzi
+}
+
+// randomHanzi picks n random characters from hanzi.
+func randomHanzi(reader io.Reader, hanzi []rune, n int) (string, error) {
+	if n < 1 || n > 64 {
+		return "", errors.New("chars must range 1-64")
+	}
+	var b strings.Builder
+	for i := 0; i < n; i++ {
+		j, err := randomIndex(reader, len(hanzi))
+		if err != nil {
+			return "", err
+		}
+		b.WriteRune(hanzi[j])
+	}
+	return b.String(), nil
+}
+
+// pinyinPassword converts the phrase into pinyin syllables, ASCII letters and
+// digits in the phrase are kept as is.
+func pinyinPassword(phrase string, conf pinyinConf) (string, error) {
+	args := pinyin.NewArgs()
+	args.Style = pinyin.Normal
+	if conf.Tone {
+		args.Style = pinyin.Tone3
+	}
+
+	var syllables []string
+	for _, r := range phrase {
+		if unicode.IsSpace(r) {
+			continue
+		}
+		if r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			syllables = append(syllables, string(r))
+			continue
+		}
+		py := pinyin.Pinyin(string(r), args)
+		if len(py) == 0 || len(py[0]) == 0 {
+			return "", fmt.Errorf("no pinyin for %q", r)
+		}
+		s := py[0][0]
+		if conf.Capitalize {
+			s = strings.ToUpper(s[:1]) + s[1:]
+		}
+		syllables = append(syllables, s)
+	}
+	if len(syllables) == 0 {
+		return "", errors.New("phrase is empty")
+	}
+	return strings.Join(syllables, conf.Separator), nil
+}
+
+func (m *PwdGenCLI) Pinyin(cmd *cobra.Command, args []string) error {
+	chars, _ := cmd.Flags().GetInt("chars")
+
+	var conf pinyinConf
+	conf.Tone, _ = cmd.Flags().GetBool("tone")
+	conf.Capitalize, _ = cmd.Flags().GetBool("capitalize")
+	conf.Separator, _ = cmd.Flags().GetString("separator")
+
+	var (
+		phrase  string
+		entropy float64
+		err     error
+	)
+	if len(args) == 0 {
+		hanzi := distinctHanzi(conf)
+		phrase, err = randomHanzi(rand.Reader, hanzi, chars)
+		if err != nil {
+			return err
+		}
+		entropy = float64(chars) * math.Log2(float64(len(hanzi)))
+	} else {
+		phrase = strings.Join(args, "")
+		entropy = float64(len([]rune(phrase))) * math.Log2(_phraseAlphabet)
+	}
+
+	s, err := pinyinPassword(phrase, conf)
+	if err != nil {
+		return err
+	}
+	if err := writeOutput(cmd, s); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(os.Stderr, "汉字: %s\n", phrase)
+	if len(args) == 0 {
+		fmt.Fprintf(os.Stderr, "熵: %.1f bits\n", entropy)
+	} else {
+		fmt.Fprintf(os.Stderr, "熵: 不超过 %.1f bits, 常见的词语和诗句远低于此值\n", entropy)
+	}
+	return nil
+}
@@ -0,0 +1,338 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+)
+
+const (
+	_minLength = 6
+	_maxLength = 2048
+)
+
+// ClassRule limits how many characters of one class a password contains.
+// Max < 0 means unlimited.
+type ClassRule struct {
+	Chars string
+	Min   int
+	Max   int
+}
+
+// Policy describes the character classes a password is built from.
+type Policy struct {
+	Length  int
+	Lower   ClassRule
+	Upper   ClassRule
+	Digits  ClassRule
+	Symbols ClassRule
+
+	// Exclude is the list of characters never used.
+	Exclude string
+	// NoAmbiguous skips look-alike characters, see Ambiguous.
+	NoAmbiguous bool
+}
+
+type namedClass struct {
+	name string
+	ClassRule
+}
+
+// classes returns the rules with excluded characters removed from each class.
+func (p Policy) classes() []namedClass {
+	exclude := p.Exclude
+	if p.NoAmbiguous {
+		exclude += Ambiguous
+	}
+	filter := func(s string) string {
+		var b strings.Builder
+		for _, r := range s {
+			if !strings.ContainsRune(exclude, r) && !strings.ContainsRune(b.String(), r) {
+				b.WriteRune(r)
+			}
+		}
+		return b.String()
+	}
+	classes := []namedClass{
+		{"lower", p.Lower},
+		{"upper", p.Upper},
+		{"digits", p.Digits},
+		{"symbols", p.Symbols},
+	}
+	for i := range classes {
+		classes[i].Chars = filter(classes[i].Chars)
+	}
+	return classes
+}
+
+// Validate reports whether a password satisfying every rule can exist.
+func (p Policy) Validate() error {
+	if p.Length < _minLength {
+		return fmt.Errorf("length must >= %d", _minLength)
+	} else if p.Length > _maxLength {
+		return errors.New("length too long")
+	}
+
+	var minSum, capacity int
+	unlimited := false
+	for _, c := range p.classes() {
+		if c.Min < 0 {
+			return fmt.Errorf("%s: min must >= 0", c.name)
+		}
+		if c.Max >= 0 && c.Max < c.Min {
+			return fmt.Errorf("%s: max %d < min %d", c.name, c.Max, c.Min)
+		}
+		for _, r := range c.Chars {
+			if r < '!' || r > '~' {
+				return fmt.Errorf("%s: only printable ASCII characters are allowed, got %q", c.name, r)
+			}
+		}
+		if c.Chars == "" {
+			if c.Min > 0 {
+				return fmt.Errorf("%s: no characters left after exclusion", c.name)
+			}
+			continue
+		}
+		minSum += c.Min
+		if c.Max < 0 {
+			unlimited = true
+		} else {
+			capacity += c.Max
+		}
+	}
+	if minSum > p.Length {
+		return fmt.Errorf("sum of min counts %d exceeds length %d", minSum, p.Length)
+	}
+	if !unlimited && capacity < p.Length {
+		return fmt.Errorf("sum of max counts %d is less than length %d", capacity, p.Length)
+	}
+	return nil
+}
+
+// Generate builds a random password that satisfies every rule of the policy.
+func (p Policy) Generate(reader io.Reader) (string, error) {
+	if err := p.Validate(); err != nil {
+		return "", err
+	}
+
+	var (
+		result  = make([]byte, 0, p.Length)
+		classes = p.classes()
+		counts  = make([]int, len(classes))
+	)
+
+	// Required characters of each class.
+	for i, c := range classes {
+		for j := 0; j < c.Min; j++ {
+			k, err := randomIndex(reader, len(c.Chars))
+			if err != nil {
+				return "", err
+			}
+			result = append(result, c.Chars[k])
+			counts[i]++
+		}
+	}
+
+	// The rest is drawn from every class that still has room, weighted by the
+	// size of its alphabet so each allowed character is equally likely.
+	for len(result) < p.Length {
+		total := 0
+		for i, c := range classes {
+			if c.Chars != "" && (c.Max < 0 || counts[i] < c.Max) {
+				total += len(c.Chars)
+			}
+		}
+		pick, err := randomIndex(reader, total)
+		if err != nil {
+			return "", err
+		}
+		for i, c := range classes {
+			if c.Chars == "" || (c.Max >= 0 && counts[i] >= c.Max) {
+				continue
+			}
+			if pick < len(c.Chars) {
+				result = append(result, c.Chars[pick])
+				counts[i]++
+				break
+			}
+			pick -= len(c.Chars)
+		}
+	}
+
+	// The required characters are at the front, shuffle them into place.
+	if err := shuffle(reader, result); err != nil {
+		return "", err
+	}
+	return string(result), nil
+}
+
+// Entropy returns the Shannon entropy of the passwords Generate produces in
+// bits. Given the number of characters of each class, the shuffled password
+// is uniform over all strings with those counts, so the entropy is that of
+// the counts plus the expected log of the number of such strings. Without
+// max counts the free characters of a class follow a binomial distribution,
+// which gives the exact value. With max counts, see EntropyIsBound.
+func (p Policy) Entropy() float64 {
+	if p.EntropyIsBound() {
+		return p.entropyBound()
+	}
+	free, pool := p.Length, 0
+	for _, c := range p.classes() {
+		if c.Chars == "" {
+			continue
+		}
+		free -= c.Min
+		if c.Max < 0 {
+			pool += len(c.Chars)
+		}
+	}
+	if pool == 0 {
+		free = 0
+	}
+
+	// In nats: ln L! - ln free! plus, for every class, the expectation of
+	// n ln|C| - ln n! - k ln q + ln k!, where k ~ Binomial(free, q) free
+	// characters are drawn and n = Min + k.
+	h := lgamma(p.Length+1) - lgamma(free+1)
+	for _, c := range p.classes() {
+		if c.Chars == "" {
+			continue
+		}
+		size := math.Log(float64(len(c.Chars)))
+		if c.Max >= 0 || free == 0 {
+			h += float64(c.Min)*size - lgamma(c.Min+1)
+			continue
+		}
+		q := float64(len(c.Chars)) / float64(pool)
+		h += binomialExpect(free, q, func(k int) float64 {
+			return float64(c.Min+k)*size - lgamma(c.Min+k+1) - float64(k)*math.Log(q) + lgamma(k+1)
+		})
+	}
+	return h / math.Ln2
+}
+
+// EntropyIsBound reports whether Entropy is only an upper bound, which is
+// the case when a class has a max count above its min. Classes then fill up
+// while drawing and the counts have no simple distribution, Entropy returns
+// log2 of the number of passwords the policy allows instead.
+func (p Policy) EntropyIsBound() bool {
+	for _, c := range p.classes() {
+		if c.Chars != "" && c.Max > c.Min {
+			return true
+		}
+	}
+	return false
+}
+
+// entropyBound returns log2 of the number of strings satisfying the policy,
+// L! times the coefficient of x^L in the product of sum |C|^n x^n / n! over
+// the allowed counts n of every class.
+func (p Policy) entropyBound() float64 {
+	coef := []float64{0} // ln of the coefficients, -Inf is zero
+	for _, c := range p.classes() {
+		if c.Chars == "" {
+			continue
+		}
+		hi := p.Length
+		if c.Max >= 0 {
+			hi = min(c.Max, p.Length)
+		}
+		size := math.Log(float64(len(c.Chars)))
+		next := make([]float64, p.Length+1)
+		for i := range next {
+			next[i] = math.Inf(-1)
+		}
+		for i, a := range coef {
+			if math.IsInf(a, -1) {
+				continue
+			}
+			for n := c.Min; n <= hi && i+n <= p.Length; n++ {
+				next[i+n] = logAdd(next[i+n], a+float64(n)*size-lgamma(n+1))
+			}
+		}
+		coef = next
+	}
+	if len(coef) <= p.Length || math.IsInf(coef[p.Length], -1) {
+		return 0
+	}
+	return (lgamma(p.Length+1) + coef[p.Length]) / math.Ln2
+}
+
+// logAdd returns ln(e^a + e^b).
+func logAdd(a, b float64) float64 {
+	if a < b {
+		a, b = b, a
+	}
+	if math.IsInf(b, -1) {
+		return a
+	}
+	return a + math.Log1p(math.Exp(b-a))
+}
+
+// binomialExpect returns E[f(k)] for k ~ Binomial(n, q).
+func binomialExpect(n int, q float64, f func(k int) float64) float64 {
+	if q >= 1 {
+		return f(n)
+	}
+	var e float64
+	for k := 0; k <= n; k++ {
+		logPMF := lgamma(n+1) - lgamma(k+1) - lgamma(n-k+1) + float64(k)*math.Log(q) + float64(n-k)*math.Log1p(-q)
+		e += math.Exp(logPMF) * f(k)
+	}
+	return e
+}
+
+// String describes the policy in one line, e.g.
+// "length=16 lower=1+ upper=1+ digits=1+ symbols=1+".
+func (p Policy) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "length=%d", p.Length)
+	for _, c := range p.classes() {
+		if c.Chars == "" || (c.Min == 0 && c.Max == 0) {
+			continue
+		}
+		switch {
+		case c.Max < 0:
+			fmt.Fprintf(&b, " %s=%d+", c.name, c.Min)
+		case c.Max == c.Min:
+			fmt.Fprintf(&b, " %s=%d", c.name, c.Min)
+		default:
+			fmt.Fprintf(&b, " %s=%d-%d", c.name, c.Min, c.Max)
+		}
+	}
+	if p.Symbols.Chars != Symbols && p.Symbols.Chars != "" {
+		fmt.Fprintf(&b, " symbol-set=%q", p.Symbols.Chars)
+	}
+	if p.Exclude != "" {
+		fmt.Fprintf(&b, " exclude=%q", p.Exclude)
+	}
+	if p.NoAmbiguous {
+		b.WriteString(" no-ambiguous")
+	}
+	return b.String()
+}
+
+func lgamma(n int) float64 {
+	v, _ := math.Lgamma(float64(n))
+	return v
+}
+
+// newPolicy returns a policy where every class enabled by the level must
+// appear at least once and has no upper bound.
+func newPolicy(level, length int) Policy {
+	rule := func(chars string, enabled bool) ClassRule {
+		if enabled {
+			return ClassRule{Chars: chars, Min: 1, Max: -1}
+		}
+		return ClassRule{Chars: chars}
+	}
+	return Policy{
+		Length:  length,
+		Lower:   rule(LowerLetters, level >= 2),
+		Upper:   rule(UpperLetters, level >= 3),
+		Digits:  rule(Digits, true),
+		Symbols: rule(Symbols, level >= 4),
+	}
+}
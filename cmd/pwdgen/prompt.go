@@ -0,0 +1,62 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/atotto/clipboard"
+	"golang.org/x/term"
+)
+
+// stdinReader is shared by every read of stdin, a reader per call would
+// buffer past its line and lose the input of the next one.
+var stdinReader = bufio.NewReader(os.Stdin)
+
+// readSecret reads a secret from the clipboard, or from the terminal without
+// echo. When stdin is not a terminal the first line of stdin is used.
+func readSecret(prompt string, fromClipboard bool) (string, error) {
+	if fromClipboard {
+		s, err := clipboard.ReadAll()
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimRight(s, "\r\n"), nil
+	}
+
+	fd := int(os.Stdin.Fd())
+	if term.IsTerminal(fd) {
+		fmt.Fprint(os.Stderr, prompt)
+		b, err := term.ReadPassword(fd)
+		fmt.Fprintln(os.Stderr)
+		if err != nil {
+			return "", err
+		}
+		return string(b), nil
+	}
+
+	line, err := stdinReader.ReadString('\n')
+	if err != nil && line == "" {
+		return "", errors.New("nothing to read from stdin")
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// readNewSecret reads a new secret like readSecret, on a terminal it is asked
+// twice so that a typo does not lock the data away.
+func readNewSecret(prompt string, fromClipboard bool) (string, error) {
+	s, err := readSecret(prompt, fromClipboard)
+	if err != nil || fromClipboard || !term.IsTerminal(int(os.Stdin.Fd())) {
+		return s, err
+	}
+	again, err := readSecret("再次输入"+prompt, false)
+	if err != nil {
+		return "", err
+	}
+	if s != again {
+		return "", errors.New("the two inputs do not match")
+	}
+	return s, nil
+}
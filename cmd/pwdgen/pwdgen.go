@@ -2,9 +2,10 @@ package main
 
 import (
 	"crypto/rand"
+	"encoding/binary"
 	"errors"
 	"io"
-	"math/big"
+	"math"
 )
 
 const (
@@ -19,6 +20,9 @@ const (
 
 	// Symbols is the list of symbols.
 	Symbols = "~!@#$%^&*()_+`-={}|[]\\:\"<>?,./"
+
+	// Ambiguous is the list of look-alike characters, e.g. 0/O, l/1/I.
+	Ambiguous = "0Oo1lI|"
 )
 
 const (
@@ -43,71 +47,7 @@ func fullPassword(level, length int) (string, error) {
 		return "", errors.New("level must range 1-4")
 	}
 
-	if length < 6 {
-		return "", errors.New("length must >= 6")
-	} else if length > 2048 {
-		return "", errors.New("length too long")
-	}
-
-	var (
-		result string
-		read   = rand.Reader
-	)
-
-	var fullConf = setLevel(level, length)
-
-	// Characters
-	for i := 0; i < fullConf.NumLowerLetters; i++ {
-		ch, err := randomElement(read, LowerLetters)
-		if err != nil {
-			return "", err
-		}
-
-		result, err = randomInsert(read, result, ch)
-		if err != nil {
-			return "", err
-		}
-	}
-
-	for i := 0; i < fullConf.NumUpperLetters; i++ {
-		ch, err := randomElement(read, UpperLetters)
-		if err != nil {
-			return "", err
-		}
-
-		result, err = randomInsert(read, result, ch)
-		if err != nil {
-			return "", err
-		}
-	}
-
-	// Digits
-	for i := 0; i < fullConf.NumDigits; i++ {
-		d, err := randomElement(read, Digits)
-		if err != nil {
-			return "", err
-		}
-
-		result, err = randomInsert(read, result, d)
-		if err != nil {
-			return "", err
-		}
-	}
-
-	// Symbols
-	for i := 0; i < fullConf.NumSymbols; i++ {
-		sym, err := randomElement(read, Symbols)
-		if err != nil {
-			return "", err
-		}
-
-		result, err = randomInsert(read, result, sym)
-		if err != nil {
-			return "", err
-		}
-	}
-
-	return result, nil
+	return setLevel(level, length).Policy().Generate(rand.Reader)
 }
 
 func setLevel(level, length int) fullPasswordConf {
@@ -139,25 +79,56 @@ func setLevel(level, length int) fullPasswordConf {
 	return fullConf
 }
 
-// randomInsert randomly inserts the given value into the given string.
-func randomInsert(reader io.Reader, s, val string) (string, error) {
-	if s == "" {
-		return val, nil
+// Policy converts the level conf into a policy with exact per-class counts.
+func (c fullPasswordConf) Policy() Policy {
+	exact := func(chars string, n int) ClassRule {
+		return ClassRule{Chars: chars, Min: n, Max: n}
+	}
+	return Policy{
+		Length:  c.NumLowerLetters + c.NumUpperLetters + c.NumDigits + c.NumSymbols,
+		Lower:   exact(LowerLetters, c.NumLowerLetters),
+		Upper:   exact(UpperLetters, c.NumUpperLetters),
+		Digits:  exact(Digits, c.NumDigits),
+		Symbols: exact(Symbols, c.NumSymbols),
 	}
+}
 
-	n, err := rand.Int(reader, big.NewInt(int64(len(s)+1)))
-	if err != nil {
-		return "", err
+// shuffle permutes b in place with the Fisher-Yates algorithm.
+func shuffle(reader io.Reader, b []byte) error {
+	for i := len(b) - 1; i > 0; i-- {
+		j, err := randomIndex(reader, i+1)
+		if err != nil {
+			return err
+		}
+		b[i], b[j] = b[j], b[i]
 	}
-	i := n.Int64()
-	return s[0:i] + val + s[i:], nil
+	return nil
 }
 
 // randomElement extracts a random element from the given string.
 func randomElement(reader io.Reader, s string) (string, error) {
-	n, err := rand.Int(reader, big.NewInt(int64(len(s))))
+	i, err := randomIndex(reader, len(s))
 	if err != nil {
 		return "", err
 	}
-	return string(s[n.Int64()]), nil
+	return string(s[i]), nil
+}
+
+// randomIndex returns a uniform random number in [0, n) by rejection
+// sampling. The result depends only on the bytes read, so a deterministic
+// reader always gives the same passwords, see derive.
+func randomIndex(reader io.Reader, n int) (int, error) {
+	if n <= 0 {
+		return 0, errors.New("random range must > 0")
+	}
+	limit := math.MaxUint64 - math.MaxUint64%uint64(n)
+	var b [8]byte
+	for {
+		if _, err := io.ReadFull(reader, b[:]); err != nil {
+			return 0, err
+		}
+		if v := binary.BigEndian.Uint64(b[:]); v < limit {
+			return int(v % uint64(n)), nil
+		}
+	}
 }
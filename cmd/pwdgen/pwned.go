@@ -0,0 +1,277 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/chirichan/mei/internal/entities"
+	"github.com/gocarina/gocsv"
+	"github.com/spf13/cobra"
+)
+
+// hibpDB looks up SHA-1 hashes in a locally downloaded Have I Been Pwned
+// dataset, nothing is sent over the network. Supported layouts:
+//
+//   - a dump of "HASH:COUNT" lines sorted by hash, searched by binary search
+//   - a directory of range files named "ABCDE.txt" with "SUFFIX:COUNT" lines
+//   - a single range file named "ABCDE.txt"
+type hibpDB struct {
+	path   string
+	dir    bool
+	prefix string // prefix of a single range file
+	file   *os.File
+	size   int64
+}
+
+func openHIBP(path string) (*hibpDB, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return &hibpDB{path: path, dir: true}, nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	first, err := bufio.NewReader(f).ReadString('\n')
+	if err != nil && first == "" {
+		f.Close()
+		return nil, fmt.Errorf("read %s err: %w", path, err)
+	}
+	db := &hibpDB{path: path, file: f, size: info.Size()}
+	switch hash, _, _ := strings.Cut(first, ":"); len(hash) {
+	case sha1.Size * 2:
+	case sha1.Size*2 - 5:
+		db.prefix = strings.ToUpper(strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)))
+		if len(db.prefix) != 5 {
+			f.Close()
+			return nil, fmt.Errorf("range file must be named after its hash prefix, e.g. 21BD1.txt: %s", path)
+		}
+	default:
+		f.Close()
+		return nil, fmt.Errorf("unknown hibp file format: %s", path)
+	}
+	return db, nil
+}
+
+func (db *hibpDB) Close() error {
+	if db.file != nil {
+		return db.file.Close()
+	}
+	return nil
+}
+
+// Lookup returns how many times the password appears in the dataset.
+func (db *hibpDB) Lookup(password string) (int, error) {
+	sum := sha1.Sum([]byte(password))
+	return db.LookupHash(strings.ToUpper(hex.EncodeToString(sum[:])))
+}
+
+// LookupHash looks up an uppercase hex SHA-1 hash.
+func (db *hibpDB) LookupHash(hash string) (int, error) {
+	prefix, suffix := hash[:5], hash[5:]
+	switch {
+	case db.dir:
+		f, err := os.Open(filepath.Join(db.path, prefix+".txt"))
+		if errors.Is(err, os.ErrNotExist) {
+			return 0, fmt.Errorf("range file %s.txt not found in %s", prefix, db.path)
+		} else if err != nil {
+			return 0, err
+		}
+		defer f.Close()
+		return scanRange(f, suffix)
+	case db.prefix != "":
+		if prefix != db.prefix {
+			return 0, fmt.Errorf("hash prefix %s is not covered by range file %s", prefix, db.path)
+		}
+		if _, err := db.file.Seek(0, io.SeekStart); err != nil {
+			return 0, err
+		}
+		return scanRange(db.file, suffix)
+	default:
+		return searchSorted(db.file, db.size, hash)
+	}
+}
+
+// scanRange scans a range file, which holds at most a few thousand lines.
+func scanRange(r io.Reader, suffix string) (int, error) {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		hash, count, ok := strings.Cut(strings.TrimSpace(scanner.Text()), ":")
+		if ok && strings.EqualFold(hash, suffix) {
+			return strconv.Atoi(count)
+		}
+	}
+	return 0, scanner.Err()
+}
+
+// searchSorted binary searches the byte offsets of a file of sorted
+// "HASH:COUNT" lines, so a multi-GB dump needs only a few dozen reads.
+func searchSorted(f io.ReaderAt, size int64, hash string) (int, error) {
+	// The line we look for starts in [lo, hi), lo is always a line start.
+	lo, hi := int64(0), size
+	for lo < hi {
+		mid := lo + (hi-lo)/2
+		line, start, next, err := lineAfter(f, size, mid)
+		if err != nil {
+			return 0, err
+		}
+		if start >= hi {
+			hi = mid
+			continue
+		}
+		h, count, _ := strings.Cut(line, ":")
+		switch c := strings.Compare(strings.ToUpper(h), hash); {
+		case c == 0:
+			return strconv.Atoi(strings.TrimSpace(count))
+		case c < 0:
+			lo = next
+		default:
+			hi = mid
+		}
+	}
+	return 0, nil
+}
+
+// lineAfter returns the first line starting at or after off, along with its
+// start offset and the offset of the line after it.
+func lineAfter(f io.ReaderAt, size, off int64) (string, int64, int64, error) {
+	start := off
+	if off > 0 {
+		// A line starts at off only if the byte before it is a newline.
+		var err error
+		if start, err = nextLineStart(f, size, off-1); err != nil {
+			return "", 0, 0, err
+		}
+	}
+	if start >= size {
+		return "", size, size, nil
+	}
+
+	buf := make([]byte, 256)
+	n, err := f.ReadAt(buf, start)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return "", 0, 0, err
+	}
+	line, next := buf[:n], start+int64(n)
+	if i := bytes.IndexByte(line, '\n'); i >= 0 {
+		line, next = line[:i], start+int64(i)+1
+	} else if next < size {
+		return "", 0, 0, fmt.Errorf("line at offset %d too long", start)
+	}
+	return strings.TrimRight(string(line), "\r"), start, next, nil
+}
+
+// nextLineStart returns the offset right after the first newline at or after off.
+func nextLineStart(f io.ReaderAt, size, off int64) (int64, error) {
+	buf := make([]byte, 256)
+	for off < size {
+		n, err := f.ReadAt(buf, off)
+		if err != nil && !errors.Is(err, io.EOF) {
+			return 0, err
+		}
+		if i := bytes.IndexByte(buf[:n], '\n'); i >= 0 {
+			return off + int64(i) + 1, nil
+		}
+		if n == 0 {
+			break
+		}
+		off += int64(n)
+	}
+	return size, nil
+}
+
+// loadBatchKeys reads the entries of a xykey json or a chrome csv file.
+func loadBatchKeys(path string) ([]entities.Key, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		var xyKey entities.XyKey
+		if err := json.Unmarshal(b, &xyKey); err != nil {
+			return nil, err
+		}
+		return xyKey.Key, nil
+	case ".csv":
+		var csvData []entities.ChromeCSV
+		if err := gocsv.UnmarshalBytes(b, &csvData); err != nil {
+			return nil, err
+		}
+		keys := make([]entities.Key, len(csvData))
+		for i, v := range csvData {
+			keys[i] = entities.Key{Name: v.Name, Account: v.Username, Password: v.Password, Url: v.URL}
+		}
+		return keys, nil
+	default:
+		return nil, fmt.Errorf("unsupported batch file %s, want .json (xykey) or .csv (chrome)", path)
+	}
+}
+
+func (m *PwdGenCLI) Pwned(cmd *cobra.Command, args []string) error {
+	dbPath, _ := cmd.Flags().GetString("db")
+	batch, _ := cmd.Flags().GetString("batch")
+	fromClipboard, _ := cmd.Flags().GetBool("clipboard")
+
+	db, err := openHIBP(dbPath)
+	if err != nil {
+		return fmt.Errorf("open hibp dataset err: %w", err)
+	}
+	defer db.Close()
+
+	if batch == "" {
+		password, err := readSecret("密码: ", fromClipboard)
+		if err != nil {
+			return err
+		}
+		count, err := db.Lookup(password)
+		if err != nil {
+			return err
+		}
+		if count > 0 {
+			fmt.Printf("该密码已泄露, 在数据集中出现 %d 次, 请立即更换。\n", count)
+		} else {
+			fmt.Println("未在数据集中找到该密码。")
+		}
+		return nil
+	}
+
+	keys, err := loadBatchKeys(batch)
+	if err != nil {
+		return fmt.Errorf("load batch file err: %w", err)
+	}
+	var checked, pwned int
+	for _, k := range keys {
+		for _, password := range []string{k.Password, k.Password2} {
+			if password == "" {
+				continue
+			}
+			checked++
+			count, err := db.Lookup(password)
+			if err != nil {
+				m.Logger.Error("lookup", "name", k.Name, "account", k.Account, "err", err)
+				continue
+			}
+			if count > 0 {
+				pwned++
+				fmt.Printf("%s\t%s\t%s\t泄露 %d 次\n", k.Name, k.Account, k.Url, count)
+			}
+		}
+	}
+	fmt.Printf("共检查 %d 个密码, 其中 %d 个已泄露。\n", checked, pwned)
+	return nil
+}
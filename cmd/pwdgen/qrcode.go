@@ -0,0 +1,89 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/boombuler/barcode"
+	"github.com/boombuler/barcode/qr"
+)
+
+const (
+	_qrQuietZone  = 2 // modules of white border
+	_qrModuleSize = 8 // pixels per module in the png file
+)
+
+// writeQR renders content as a QR code, target "term" prints it to the
+// terminal, any other value is the path of a png file.
+func writeQR(target, content string) error {
+	code, err := qr.Encode(content, qr.M, qr.Auto)
+	if err != nil {
+		return fmt.Errorf("encode qr code err: %w", err)
+	}
+	if target == "term" {
+		return printQR(os.Stdout, code)
+	}
+	f, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return png.Encode(f, qrImage(code))
+}
+
+func qrDark(code barcode.Barcode, x, y int) bool {
+	b := code.Bounds()
+	if x < b.Min.X || x >= b.Max.X || y < b.Min.Y || y >= b.Max.Y {
+		return false
+	}
+	return color.GrayModel.Convert(code.At(x, y)).(color.Gray).Y < 128
+}
+
+// printQR prints the code with half-block characters, two modules per line.
+// Light modules are drawn, so it is meant for terminals with dark background.
+func printQR(w io.Writer, code barcode.Barcode) error {
+	b := code.Bounds()
+	var sb strings.Builder
+	for y := b.Min.Y - _qrQuietZone; y < b.Max.Y+_qrQuietZone; y += 2 {
+		for x := b.Min.X - _qrQuietZone; x < b.Max.X+_qrQuietZone; x++ {
+			top, bottom := !qrDark(code, x, y), !qrDark(code, x, y+1)
+			switch {
+			case top && bottom:
+				sb.WriteRune('█')
+			case top:
+				sb.WriteRune('▀')
+			case bottom:
+				sb.WriteRune('▄')
+			default:
+				sb.WriteRune(' ')
+			}
+		}
+		sb.WriteByte('\n')
+	}
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// qrImage scales the code and adds the quiet zone.
+func qrImage(code barcode.Barcode) image.Image {
+	b := code.Bounds()
+	size := (b.Dx() + 2*_qrQuietZone) * _qrModuleSize
+	img := image.NewGray(image.Rect(0, 0, size, size))
+	for py := 0; py < size; py++ {
+		for px := 0; px < size; px++ {
+			x := b.Min.X + px/_qrModuleSize - _qrQuietZone
+			y := b.Min.Y + py/_qrModuleSize - _qrQuietZone
+			if qrDark(code, x, y) {
+				img.SetGray(px, py, color.Gray{Y: 0})
+			} else {
+				img.SetGray(px, py, color.Gray{Y: 255})
+			}
+		}
+	}
+	return img
+}
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// writeFileAtomic writes dst through a temp file in the same directory that
+// is synced and renamed over dst, so dst is either complete or untouched.
+func writeFileAtomic(dst string, perm os.FileMode, write func(w io.Writer) error) (err error) {
+	tmp, err := os.CreateTemp(filepath.Dir(dst), "."+filepath.Base(dst)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tmp.Close()
+			os.Remove(tmp.Name())
+		}
+	}()
+
+	bw := bufio.NewWriterSize(tmp, _chunkSize)
+	if err := write(bw); err != nil {
+		return err
+	}
+	if err := bw.Flush(); err != nil {
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), perm); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), dst)
+}
+
+// checkOverwrite returns an error if path exists, unless force is set. An
+// older encrypted file may be the only copy left of its data.
+func checkOverwrite(path string, force bool) error {
+	if force {
+		return nil
+	}
+	if _, err := os.Lstat(path); err == nil {
+		return fmt.Errorf("%s already exists, use --force to overwrite", path)
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+func sha256File(path string) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return nil, err
+	}
+	return h.Sum(nil), nil
+}
+
+// verifyContainerFile decrypts the container and compares it with the file.
+func verifyContainerFile(container, file string, secret func(kdf byte) (string, error)) error {
+	r, size, closer, err := openContainerFile(container, secret)
+	if err != nil {
+		return err
+	}
+	defer closer.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, io.NewSectionReader(r, 0, size)); err != nil {
+		return err
+	}
+	want, err := sha256File(file)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(h.Sum(nil), want) {
+		return fmt.Errorf("sha256 of %s does not match", file)
+	}
+	return nil
+}
+
+// verifyContainerZip verifies the encrypted zip against dir, see verifyZip.
+func verifyContainerZip(container, dir string, secret func(kdf byte) (string, error)) ([]string, error) {
+	r, size, closer, err := openContainerFile(container, secret)
+	if err != nil {
+		return nil, err
+	}
+	defer closer.Close()
+	return verifyZip(r, size, dir)
+}
+
+// verifyZip compares every file of the zip with the file of the same name in
+// dir and returns the paths of the files.
+func verifyZip(r io.ReaderAt, size int64, dir string) ([]string, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, entry := range zr.File {
+		if entry.FileInfo().IsDir() {
+			continue
+		}
+		path := filepath.Join(dir, filepath.FromSlash(entry.Name))
+		rc, err := entry.Open()
+		if err != nil {
+			return nil, err
+		}
+		h := sha256.New()
+		// The zip reader checks the CRC32 at EOF.
+		_, err = io.Copy(h, rc)
+		rc.Close()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", entry.Name, err)
+		}
+		want, err := sha256File(path)
+		if err != nil {
+			return nil, err
+		}
+		if !bytes.Equal(h.Sum(nil), want) {
+			return nil, fmt.Errorf("sha256 of %s does not match", path)
+		}
+		files = append(files, path)
+	}
+	return files, nil
+}
+
+// removeSource removes the file, with passes > 0 it is overwritten with
+// random data that many times first. Overwriting is best effort, SSDs and
+// copy-on-write file systems may keep the old blocks.
+func removeSource(path string, passes int) error {
+	if passes > 0 {
+		f, err := os.OpenFile(path, os.O_WRONLY, 0)
+		if err != nil {
+			return err
+		}
+		info, err := f.Stat()
+		if err != nil {
+			f.Close()
+			return err
+		}
+		for range passes {
+			if _, err := f.Seek(0, io.SeekStart); err != nil {
+				f.Close()
+				return err
+			}
+			if _, err := io.CopyN(f, rand.Reader, info.Size()); err != nil {
+				f.Close()
+				return err
+			}
+			if err := f.Sync(); err != nil {
+				f.Close()
+				return err
+			}
+		}
+		if err := f.Close(); err != nil {
+			return err
+		}
+	}
+	return os.Remove(path)
+}
+
+// removeFolderFiles removes the files of the folder, then the directories
+// left empty. Files that were not archived, e.g. ignored ones, stay.
+func removeFolderFiles(root string, files []string, passes int) error {
+	for _, file := range files {
+		if err := removeSource(file, passes); err != nil {
+			return err
+		}
+	}
+	var dirs []string
+	err := filepath.WalkDir(root, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			dirs = append(dirs, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	// Deepest first, so parents are empty by the time they are reached.
+	slices.SortFunc(dirs, func(a, b string) int {
+		return strings.Count(b, string(os.PathSeparator)) - strings.Count(a, string(os.PathSeparator))
+	})
+	for _, dir := range dirs {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return err
+		}
+		if len(entries) > 0 {
+			continue
+		}
+		if err := os.Remove(dir); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestWriteFileAtomic(t *testing.T) {
+	dir := t.TempDir()
+	dst := filepath.Join(dir, "out.txt")
+	if err := os.WriteFile(dst, []byte("old"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	failed := errors.New("write failed")
+	err := writeFileAtomic(dst, 0o600, func(w io.Writer) error {
+		io.WriteString(w, "partial")
+		return failed
+	})
+	if !errors.Is(err, failed) {
+		t.Fatalf("got %v, want %v", err, failed)
+	}
+	if b, _ := os.ReadFile(dst); string(b) != "old" {
+		t.Errorf("dst changed to %q by a failed write", b)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("temp file left behind: %v", entries)
+	}
+
+	if err := writeFileAtomic(dst, 0o600, func(w io.Writer) error {
+		_, err := io.WriteString(w, "new")
+		return err
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := os.ReadFile(dst); string(b) != "new" {
+		t.Errorf("got %q, want %q", b, "new")
+	}
+	if info, _ := os.Stat(dst); info.Mode().Perm() != 0o600 {
+		t.Errorf("got mode %v, want %v", info.Mode().Perm(), os.FileMode(0o600))
+	}
+}
+
+func TestVerifyContainerFile(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "a.txt")
+	container := src + Aes256Suffix
+	if err := os.WriteFile(src, []byte("hello"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := encryptContainerFile(_kdfKey, _testKey, src, container); err != nil {
+		t.Fatal(err)
+	}
+	secret := func(byte) (string, error) { return _testKey, nil }
+
+	if err := verifyContainerFile(container, src, secret); err != nil {
+		t.Errorf("verify of an identical file: %v", err)
+	}
+	if err := os.WriteFile(src, []byte("hellO"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := verifyContainerFile(container, src, secret); err == nil {
+		t.Error("verify of a changed file succeeded")
+	}
+}
+
+// A decrypt that fails must keep the encrypted file even with removeSrc.
+func TestDecryptJobKeepsSourceOnFailure(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "a.txt")
+	if err := os.WriteFile(src, []byte("hello"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	container := src + Aes256Suffix
+	if err := encryptContainerFile(_kdfKey, _testKey, src, container); err != nil {
+		t.Fatal(err)
+	}
+	secret := func(byte) (string, error) { return _testKey, nil }
+	out := t.TempDir()
+
+	// The output exists and --force is not given.
+	if err := os.WriteFile(filepath.Join(out, "a.txt"), []byte("mine"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	job := decryptJob{src: container, dir: out}
+	if err := job.run(secret, true, 0); err == nil {
+		t.Error("existing output overwritten without force")
+	}
+	if b, _ := os.ReadFile(filepath.Join(out, "a.txt")); string(b) != "mine" {
+		t.Errorf("existing output changed to %q", b)
+	}
+	if _, err := os.Stat(container); err != nil {
+		t.Errorf("source removed after a failed decrypt: %v", err)
+	}
+
+	// The container is corrupted.
+	data, err := os.ReadFile(container)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(container, flipBit(data, len(data)-1), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	job.force = true
+	if err := job.run(secret, true, 0); err == nil {
+		t.Error("corrupted container decrypted")
+	}
+	if _, err := os.Stat(container); err != nil {
+		t.Errorf("source removed after a failed decrypt: %v", err)
+	}
+
+	// Once it decrypts and verifies the source goes.
+	if err := os.WriteFile(container, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := job.run(secret, true, 1); err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := os.ReadFile(filepath.Join(out, "a.txt")); string(b) != "hello" {
+		t.Errorf("got %q, want %q", b, "hello")
+	}
+	if _, err := os.Stat(container); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("source kept after a verified decrypt: %v", err)
+	}
+}
+
+func TestRemoveFolderFiles(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		"a.txt":         "a",
+		"sub/b.txt":     "b",
+		"sub/deep/c.go": "c",
+		"kept/d.log":    "d",
+	}
+	for name, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	archived := []string{
+		filepath.Join(root, "a.txt"),
+		filepath.Join(root, "sub", "b.txt"),
+		filepath.Join(root, "sub", "deep", "c.go"),
+	}
+	if err := removeFolderFiles(root, archived, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	var left []string
+	filepath.WalkDir(root, func(path string, d os.DirEntry, err error) error {
+		if err == nil && path != root {
+			rel, _ := filepath.Rel(root, path)
+			left = append(left, filepath.ToSlash(rel))
+		}
+		return nil
+	})
+	if want := []string{"kept", "kept/d.log"}; !slices.Equal(left, want) {
+		t.Errorf("left %v, want %v", left, want)
+	}
+}
+
+// Encrypting must not replace an existing encrypted file unless --force is
+// given, it may be the only copy left after --remove-source.
+func TestEncryptKeepsExistingOutput(t *testing.T) {
+	dir := t.TempDir()
+	out := t.TempDir()
+	src := filepath.Join(dir, "a.txt")
+	folder := filepath.Join(dir, "docs")
+	if err := os.MkdirAll(folder, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{src, filepath.Join(folder, "b.txt")} {
+		if err := os.WriteFile(path, []byte("new"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, target := range []string{src, folder} {
+		existing := filepath.Join(out, filepath.Base(target)+Aes256Suffix)
+		if target == folder {
+			existing = filepath.Join(out, "docs.zip"+Aes256Suffix)
+		}
+		if err := os.WriteFile(existing, []byte("old"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+
+		encrypt := func(extra ...string) error {
+			cmd := NewCLI()
+			cmd.SetArgs(append([]string{"encrypt", "-f", target, "--output-dir", out, "--key", _testKey}, extra...))
+			cmd.SetOut(io.Discard)
+			cmd.SetErr(io.Discard)
+			return cmd.Execute()
+		}
+		if err := encrypt(); err == nil {
+			t.Errorf("%s: existing output replaced without --force", target)
+		}
+		if b, _ := os.ReadFile(existing); string(b) != "old" {
+			t.Errorf("%s: existing output changed", target)
+		}
+
+		if err := encrypt("--force"); err != nil {
+			t.Fatalf("%s: %v", target, err)
+		}
+		if ok, err := isContainerFile(existing); err != nil || !ok {
+			t.Errorf("%s: output not replaced with --force: %v, %v", target, ok, err)
+		}
+	}
+}
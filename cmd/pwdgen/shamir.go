@@ -0,0 +1,294 @@
+package main
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"math/big"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// gfExp and gfLog are the exponent and logarithm tables of GF(256) with the
+// AES polynomial x^8+x^4+x^3+x+1 and generator 3.
+var gfExp, gfLog = func() ([510]byte, [256]byte) {
+	var (
+		exp [510]byte
+		log [256]byte
+		x   byte = 1
+	)
+	for i := 0; i < 255; i++ {
+		exp[i], exp[i+255] = x, x
+		log[x] = byte(i)
+		// x *= 3
+		hi := x & 0x80
+		x2 := x << 1
+		if hi != 0 {
+			x2 ^= 0x1b
+		}
+		x ^= x2
+	}
+	return exp, log
+}()
+
+func gfMul(a, b byte) byte {
+	if a == 0 || b == 0 {
+		return 0
+	}
+	return gfExp[int(gfLog[a])+int(gfLog[b])]
+}
+
+func gfDiv(a, b byte) byte {
+	if a == 0 {
+		return 0
+	}
+	return gfExp[int(gfLog[a])+255-int(gfLog[b])]
+}
+
+// A share is x, threshold, the kind of the secret, one y per secret byte and
+// the CRC32 of them, so that typos and missing shares are caught before
+// combining.
+const (
+	_shareHeader   = 3
+	_shareOverhead = _shareHeader + crc32.Size
+)
+
+// The kind of a secret tells combine how to print it.
+const (
+	// _shareKey is a binary key, printed as hex.
+	_shareKey byte = 1
+	// _shareText is any text, e.g. a password, printed as is.
+	_shareText byte = 2
+)
+
+// shamirSplit splits the secret into n shares, any k of them recover it.
+func shamirSplit(reader io.Reader, secret []byte, kind byte, n, k int) ([][]byte, error) {
+	if k < 2 || k > n || n > 255 {
+		return nil, errors.New("need 2 <= threshold <= shares <= 255")
+	}
+	if len(secret) == 0 {
+		return nil, errors.New("secret is empty")
+	}
+
+	shares := make([][]byte, n)
+	for i := range shares {
+		shares[i] = make([]byte, _shareHeader, len(secret)+_shareOverhead)
+		shares[i][0], shares[i][1], shares[i][2] = byte(i+1), byte(k), kind
+	}
+	coeffs := make([]byte, k)
+	for _, s := range secret {
+		// A random polynomial of degree k-1 whose constant term is the byte.
+		coeffs[0] = s
+		if _, err := io.ReadFull(reader, coeffs[1:]); err != nil {
+			return nil, err
+		}
+		for i := range shares {
+			x, y := shares[i][0], byte(0)
+			for j := k - 1; j >= 0; j-- {
+				y = gfMul(y, x) ^ coeffs[j]
+			}
+			shares[i] = append(shares[i], y)
+		}
+	}
+	clear(coeffs)
+	for i := range shares {
+		shares[i] = binary.BigEndian.AppendUint32(shares[i], crc32.ChecksumIEEE(shares[i]))
+	}
+	return shares, nil
+}
+
+// shamirCombine recovers the secret and its kind from at least threshold
+// shares.
+func shamirCombine(shares [][]byte) ([]byte, byte, error) {
+	if len(shares) == 0 {
+		return nil, 0, errors.New("no shares")
+	}
+	seen := make(map[byte]bool)
+	for i, s := range shares {
+		if len(s) <= _shareOverhead {
+			return nil, 0, fmt.Errorf("share %d is too short", i+1)
+		}
+		body := s[:len(s)-crc32.Size]
+		if crc32.ChecksumIEEE(body) != binary.BigEndian.Uint32(s[len(body):]) {
+			return nil, 0, fmt.Errorf("share %d: checksum mismatch, check for typos", i+1)
+		}
+		if len(s) != len(shares[0]) || s[1] != shares[0][1] || s[2] != shares[0][2] {
+			return nil, 0, fmt.Errorf("share %d does not belong to the same secret", i+1)
+		}
+		if s[0] == 0 || seen[s[0]] {
+			return nil, 0, fmt.Errorf("share %d: duplicate or invalid index %d", i+1, s[0])
+		}
+		seen[s[0]] = true
+	}
+	if k := int(shares[0][1]); len(shares) < k {
+		return nil, 0, fmt.Errorf("need %d shares, got %d", k, len(shares))
+	}
+
+	// Lagrange interpolation at x = 0.
+	secret := make([]byte, len(shares[0])-_shareOverhead)
+	for i, si := range shares {
+		basis := byte(1)
+		for j, sj := range shares {
+			if i != j {
+				basis = gfMul(basis, gfDiv(sj[0], sj[0]^si[0]))
+			}
+		}
+		for b := range secret {
+			secret[b] ^= gfMul(basis, si[_shareHeader+b])
+		}
+	}
+	return secret, shares[0][2], nil
+}
+
+// encodeShareWords encodes the share as words of the EFF wordlist, a base
+// 7776 number with a leading 1 byte that keeps the leading zeros.
+func encodeShareWords(share []byte, words []string) string {
+	var (
+		n    = new(big.Int).SetBytes(append([]byte{1}, share...))
+		base = big.NewInt(int64(len(words)))
+		mod  = new(big.Int)
+		out  []string
+	)
+	for n.Sign() > 0 {
+		n.DivMod(n, base, mod)
+		out = append(out, words[mod.Int64()])
+	}
+	for i, j := 0, len(out)-1; i < j; i, j = i+1, j-1 {
+		out[i], out[j] = out[j], out[i]
+	}
+	return strings.Join(out, " ")
+}
+
+// decodeShare decodes a share printed as hex or words.
+func decodeShare(s string, words []string) ([]byte, error) {
+	s = strings.TrimSpace(s)
+	if b, err := hex.DecodeString(s); err == nil {
+		return b, nil
+	}
+
+	index := make(map[string]int, len(words))
+	for i, w := range words {
+		index[w] = i
+	}
+	var (
+		n    = new(big.Int)
+		base = big.NewInt(int64(len(words)))
+	)
+	for _, w := range strings.Fields(strings.ToLower(s)) {
+		i, ok := index[w]
+		if !ok {
+			return nil, fmt.Errorf("unknown word %q", w)
+		}
+		n.Mul(n, base).Add(n, big.NewInt(int64(i)))
+	}
+	b := n.Bytes()
+	if len(b) == 0 || b[0] != 1 {
+		return nil, errors.New("invalid share")
+	}
+	return b[1:], nil
+}
+
+func (m *PwdGenCLI) ShamirSplit(cmd *cobra.Command, args []string) error {
+	n, _ := cmd.Flags().GetInt("shares")
+	k, _ := cmd.Flags().GetInt("threshold")
+	asWords, _ := cmd.Flags().GetBool("words")
+	fromClipboard, _ := cmd.Flags().GetBool("clipboard")
+
+	text, _ := cmd.Flags().GetBool("text")
+
+	prompt := "hex key 或助记词: "
+	if text {
+		prompt = "secret: "
+	}
+	s, err := readSecret(prompt, fromClipboard)
+	if err != nil {
+		return err
+	}
+	// A key is split as raw bytes, so shares are half as long as with the
+	// hex text, combine prints it as hex again.
+	var (
+		key  []byte
+		kind = _shareKey
+	)
+	if text {
+		key, kind = []byte(s), _shareText
+	} else if s = strings.TrimSpace(s); isMnemonic(s) {
+		if key, err = mnemonicToKey(s); err != nil {
+			return fmt.Errorf("parse mnemonic err: %w", err)
+		}
+	} else if key, err = hex.DecodeString(s); err != nil {
+		return fmt.Errorf("decode hex key err: %w", err)
+	}
+	shares, err := shamirSplit(rand.Reader, key, kind, n, k)
+	clear(key)
+	if err != nil {
+		return err
+	}
+	words, err := loadWordlist("")
+	if err != nil {
+		return err
+	}
+	for i, share := range shares {
+		s := hex.EncodeToString(share)
+		if asWords {
+			s = encodeShareWords(share, words)
+		}
+		fmt.Printf("%d/%d: %s\n", i+1, n, s)
+	}
+	fmt.Fprintf(os.Stderr, "任意 %d 份即可恢复, 使用 pwdgen shamir combine\n", k)
+	return nil
+}
+
+func (m *PwdGenCLI) ShamirCombine(cmd *cobra.Command, args []string) error {
+	lines := args
+	if len(lines) == 0 {
+		fmt.Fprintln(os.Stderr, "每行输入一份, 空行结束:")
+		scanner := bufio.NewScanner(stdinReader)
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if line == "" {
+				break
+			}
+			lines = append(lines, line)
+		}
+		if err := scanner.Err(); err != nil {
+			return err
+		}
+	}
+
+	words, err := loadWordlist("")
+	if err != nil {
+		return err
+	}
+	var shares [][]byte
+	for i, line := range lines {
+		// The "1/5: " prefix printed by split is optional.
+		if prefix, rest, ok := strings.Cut(line, ": "); ok && strings.Contains(prefix, "/") {
+			line = rest
+		}
+		share, err := decodeShare(line, words)
+		if err != nil {
+			return fmt.Errorf("share %d: %w", i+1, err)
+		}
+		shares = append(shares, share)
+	}
+	secret, kind, err := shamirCombine(shares)
+	if err != nil {
+		return err
+	}
+	switch kind {
+	case _shareKey:
+		return writeOutput(cmd, hex.EncodeToString(secret))
+	case _shareText:
+		return writeOutput(cmd, string(secret))
+	default:
+		return fmt.Errorf("unsupported secret kind %d, upgrade pwdgen", kind)
+	}
+}
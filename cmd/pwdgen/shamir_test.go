@@ -0,0 +1,103 @@
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"testing"
+)
+
+func TestShamirRoundTrip(t *testing.T) {
+	secret := make([]byte, 32)
+	rand.Read(secret)
+	shares, err := shamirSplit(rand.Reader, secret, _shareKey, 5, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Every subset of at least 3 shares recovers the secret.
+	for mask := 0; mask < 1<<len(shares); mask++ {
+		var subset [][]byte
+		for i, share := range shares {
+			if mask&(1<<i) != 0 {
+				subset = append(subset, share)
+			}
+		}
+		got, kind, err := shamirCombine(subset)
+		if len(subset) < 3 {
+			if err == nil {
+				t.Errorf("subset %05b: %d shares combined, need 3", mask, len(subset))
+			}
+			continue
+		}
+		if err != nil || !bytes.Equal(got, secret) || kind != _shareKey {
+			t.Errorf("subset %05b: got %x, %d, %v", mask, got, kind, err)
+		}
+	}
+}
+
+func TestShamirText(t *testing.T) {
+	secret := []byte("ab")
+	shares, err := shamirSplit(rand.Reader, secret, _shareText, 2, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, kind, err := shamirCombine(shares)
+	if err != nil || string(got) != "ab" || kind != _shareText {
+		t.Errorf("got %q, %d, %v", got, kind, err)
+	}
+}
+
+func TestShamirCombineInvalid(t *testing.T) {
+	shares, err := shamirSplit(rand.Reader, []byte("secret"), _shareText, 3, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := shamirSplit(rand.Reader, []byte("secreT!"), _shareText, 3, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherKind, err := shamirSplit(rand.Reader, []byte("secret"), _shareKey, 3, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	typo := bytes.Clone(shares[1])
+	typo[4] ^= 1
+
+	tests := map[string][][]byte{
+		"no shares":       nil,
+		"too few":         {shares[0]},
+		"duplicate index": {shares[0], shares[0]},
+		"typo":            {shares[0], typo},
+		"other secret":    {shares[0], other[1]},
+		"other kind":      {shares[0], otherKind[1]},
+		"too short":       {shares[0], shares[1][:_shareOverhead]},
+	}
+	for name, subset := range tests {
+		if _, _, err := shamirCombine(subset); err == nil {
+			t.Errorf("%s: combined", name)
+		}
+	}
+
+	if _, err := shamirSplit(rand.Reader, []byte("s"), _shareText, 3, 4); err == nil {
+		t.Error("threshold above shares accepted")
+	}
+	if _, err := shamirSplit(rand.Reader, nil, _shareText, 3, 2); err == nil {
+		t.Error("empty secret accepted")
+	}
+}
+
+func TestShareWords(t *testing.T) {
+	words, err := loadWordlist("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, share := range [][]byte{{0, 0, 1}, {1, 2, 0xff, 0}, bytes.Repeat([]byte{0xab}, 40)} {
+		got, err := decodeShare(encodeShareWords(share, words), words)
+		if err != nil || !bytes.Equal(got, share) {
+			t.Errorf("%x: got %x, %v", share, got, err)
+		}
+	}
+	if _, err := decodeShare("notaword abacus", words); err == nil {
+		t.Error("unknown word accepted")
+	}
+}
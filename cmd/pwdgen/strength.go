@@ -0,0 +1,565 @@
+package main
+
+import (
+	_ "embed"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/spf13/cobra"
+)
+
+//go:embed wordlist/common_passwords.txt
+var commonPasswords string
+
+// dictionary maps a lowercase word to its rank, common passwords come first
+// and then the EFF wordlist.
+var dictionary = func() map[string]int {
+	dict := make(map[string]int)
+	add := func(text string) {
+		words, _ := parseWordlist(strings.NewReader(text))
+		for _, w := range words {
+			if _, ok := dict[w]; !ok {
+				dict[w] = len(dict) + 1
+			}
+		}
+	}
+	add(commonPasswords)
+	add(effLargeWordlist)
+	return dict
+}()
+
+var (
+	// keyboardRows are the rows and columns of a qwerty keyboard, two keys are
+	// adjacent when they are next to each other in one of these strings.
+	keyboardRows = []string{
+		"`1234567890-=", "qwertyuiop[]\\", "asdfghjkl;'", "zxcvbnm,./",
+		"1qaz", "2wsx", "3edc", "4rfv", "5tgb", "6yhn", "7ujm", "8ik,", "9ol.", "0p;/",
+	}
+
+	// l33tTables undo common l33t substitutions, "1" may stand for "i" or "l".
+	l33tTables = []map[rune]rune{
+		{'4': 'a', '@': 'a', '3': 'e', '1': 'i', '!': 'i', '0': 'o', '5': 's', '$': 's', '7': 't', '+': 't', '2': 'z'},
+		{'4': 'a', '@': 'a', '3': 'e', '1': 'l', '|': 'l', '0': 'o', '5': 's', '$': 's', '7': 't', '+': 't', '2': 'z'},
+	}
+
+	dateRegexp = regexp.MustCompile(`\d{1,4}[-/._]\d{1,2}[-/._]\d{1,4}`)
+)
+
+const (
+	// _maxAnalyzed is the number of runes searched for patterns, like zxcvbn
+	// the search is superlinear in the length.
+	_maxAnalyzed = 256
+
+	// _minL33tMatch is the minimum length of a l33t word, shorter ones turn
+	// up in random passwords all the time.
+	_minL33tMatch = 5
+)
+
+const (
+	_kindDictionary = "dictionary"
+	_kindKeyboard   = "keyboard"
+	_kindRepeat     = "repeat"
+	_kindSequence   = "sequence"
+	_kindDate       = "date"
+)
+
+// match is a guessable pattern covering runes [i, j) of the password.
+type match struct {
+	i, j    int
+	kind    string
+	token   string
+	entropy float64
+	l33t    bool
+}
+
+// Strength is the result of analyzePassword.
+type Strength struct {
+	Entropy  float64
+	Score    int
+	Warnings []string
+}
+
+var scoreNames = []string{"非常弱", "弱", "一般", "强", "非常强"}
+
+func (s Strength) ScoreName() string {
+	return scoreNames[s.Score]
+}
+
+// CrackSeconds returns the average time to guess the password in seconds.
+func (s Strength) CrackSeconds(guessesPerSecond float64) float64 {
+	return math.Pow(2, s.Entropy-1) / guessesPerSecond
+}
+
+// analyzePassword estimates the entropy of a password in the way of zxcvbn,
+// the password is split into the guessable patterns and brute-force
+// characters that give the lowest total entropy.
+func analyzePassword(password string) Strength {
+	all := []rune(password)
+	charBits := math.Log2(float64(poolSize(all)))
+
+	// Patterns are only searched in the first _maxAnalyzed runes, the rest
+	// counts as brute force.
+	rs := all[:min(len(all), _maxAnalyzed)]
+
+	var matches []match
+	matches = append(matches, dictionaryMatches(rs)...)
+	matches = append(matches, keyboardMatches(rs)...)
+	matches = append(matches, repeatMatches(rs, charBits)...)
+	matches = append(matches, sequenceMatches(rs)...)
+	matches = append(matches, dateMatches(string(rs))...)
+
+	// bits[k] is the minimum entropy of rs[:k], last[k] the match ending at k.
+	bits := make([]float64, len(rs)+1)
+	last := make([]*match, len(rs)+1)
+	for k := 1; k <= len(rs); k++ {
+		bits[k] = bits[k-1] + charBits
+		for i := range matches {
+			m := &matches[i]
+			if m.j == k && bits[m.i]+m.entropy < bits[k] {
+				bits[k] = bits[m.i] + m.entropy
+				last[k] = m
+			}
+		}
+	}
+
+	var s Strength
+	s.Entropy = bits[len(rs)] + float64(len(all)-len(rs))*charBits
+	for k := len(rs); k > 0; {
+		m := last[k]
+		if m == nil {
+			k--
+			continue
+		}
+		s.Warnings = append([]string{m.warning()}, s.Warnings...)
+		k = m.i
+	}
+
+	if len(all) < 12 {
+		s.Warnings = append(s.Warnings, fmt.Sprintf("长度只有 %d, 建议至少 12 位", len(all)))
+	}
+	if classes := charClasses(all); classes == 1 {
+		s.Warnings = append(s.Warnings, "只包含一类字符")
+	}
+
+	switch {
+	case s.Entropy < 28:
+		s.Score = 0
+	case s.Entropy < 36:
+		s.Score = 1
+	case s.Entropy < 60:
+		s.Score = 2
+	case s.Entropy < 80:
+		s.Score = 3
+	default:
+		s.Score = 4
+	}
+	return s
+}
+
+func (m match) warning() string {
+	switch m.kind {
+	case _kindDictionary:
+		if m.l33t {
+			return fmt.Sprintf("包含常见单词 %q 的 l33t 变形, 这类替换很容易被猜到", m.token)
+		}
+		return fmt.Sprintf("包含常见单词或密码 %q", m.token)
+	case _kindKeyboard:
+		return fmt.Sprintf("包含键盘序列 %q", m.token)
+	case _kindRepeat:
+		return fmt.Sprintf("包含重复 %q", m.token)
+	case _kindSequence:
+		return fmt.Sprintf("包含连续序列 %q", m.token)
+	case _kindDate:
+		return fmt.Sprintf("包含日期 %q", m.token)
+	default:
+		return fmt.Sprintf("包含可猜测的片段 %q", m.token)
+	}
+}
+
+// poolSize returns the size of the alphabet the characters are drawn from.
+func poolSize(rs []rune) int {
+	var lower, upper, digit, symbol, other bool
+	for _, r := range rs {
+		switch {
+		case 'a' <= r && r <= 'z':
+			lower = true
+		case 'A' <= r && r <= 'Z':
+			upper = true
+		case '0' <= r && r <= '9':
+			digit = true
+		case r < utf8.RuneSelf:
+			symbol = true
+		default:
+			other = true
+		}
+	}
+	size := 0
+	for _, v := range []struct {
+		ok bool
+		n  int
+	}{{lower, 26}, {upper, 26}, {digit, 10}, {symbol, 33}, {other, 100}} {
+		if v.ok {
+			size += v.n
+		}
+	}
+	return max(size, 1)
+}
+
+func charClasses(rs []rune) int {
+	seen := make(map[int]bool)
+	for _, r := range rs {
+		switch {
+		case unicode.IsLower(r):
+			seen[0] = true
+		case unicode.IsUpper(r):
+			seen[1] = true
+		case unicode.IsDigit(r):
+			seen[2] = true
+		default:
+			seen[3] = true
+		}
+	}
+	return len(seen)
+}
+
+// uppercaseBits is the extra entropy of the capitalization of a word.
+func uppercaseBits(rs []rune) float64 {
+	var upper int
+	for _, r := range rs {
+		if unicode.IsUpper(r) {
+			upper++
+		}
+	}
+	switch {
+	case upper == 0:
+		return 0
+	case upper == len(rs), upper == 1 && unicode.IsUpper(rs[0]):
+		return 1
+	default:
+		return float64(min(upper, len(rs)-upper)) + 1
+	}
+}
+
+func dictionaryMatches(rs []rune) []match {
+	var matches []match
+	lower := []rune(strings.ToLower(string(rs)))
+	if len(lower) != len(rs) {
+		return nil
+	}
+	for t := -1; t < len(l33tTables); t++ {
+		word := lower
+		if t >= 0 {
+			word = make([]rune, len(lower))
+			for i, r := range lower {
+				if sub, ok := l33tTables[t][r]; ok {
+					r = sub
+				}
+				word[i] = r
+			}
+		}
+		for i := range word {
+			for j := i + 4; j <= len(word); j++ {
+				rank, ok := dictionary[string(word[i:j])]
+				if !ok {
+					continue
+				}
+				var subs int
+				for k := i; k < j; k++ {
+					if word[k] != lower[k] {
+						subs++
+					}
+				}
+				// A l33t word needs more letters than substitutions, or
+				// random strings like "h@5h" would count as words.
+				if t >= 0 && (subs == 0 || j-i < _minL33tMatch || subs*2 > j-i) {
+					continue
+				}
+				matches = append(matches, match{
+					i: i, j: j,
+					kind:    _kindDictionary,
+					token:   string(rs[i:j]),
+					entropy: math.Log2(float64(rank)) + uppercaseBits(rs[i:j]) + float64(subs),
+					l33t:    subs > 0,
+				})
+			}
+		}
+	}
+	return matches
+}
+
+// adjacent reports whether a and b are neighbouring keys and which row or
+// column and direction they share.
+func adjacent(a, b rune) (int, bool) {
+	for i, row := range keyboardRows {
+		x, y := strings.IndexRune(row, a), strings.IndexRune(row, b)
+		if x < 0 || y < 0 {
+			continue
+		}
+		if y == x+1 {
+			return i * 2, true
+		}
+		if y == x-1 {
+			return i*2 + 1, true
+		}
+	}
+	return 0, false
+}
+
+func keyboardMatches(rs []rune) []match {
+	var matches []match
+	lower := []rune(strings.ToLower(string(rs)))
+	if len(lower) != len(rs) {
+		return nil
+	}
+	for i := 0; i < len(lower); {
+		j, turns, dir := i+1, 0, -1
+		for ; j < len(lower); j++ {
+			d, ok := adjacent(lower[j-1], lower[j])
+			if !ok {
+				break
+			}
+			if dir >= 0 && d != dir {
+				turns++
+			}
+			dir = d
+		}
+		if j-i >= 4 {
+			matches = append(matches, match{
+				i: i, j: j,
+				kind:    _kindKeyboard,
+				token:   string(rs[i:j]),
+				entropy: math.Log2(47) + float64(turns+1)*2 + math.Log2(float64(j-i)) + uppercaseBits(rs[i:j]),
+			})
+			i = j
+		} else {
+			i++
+		}
+	}
+	return matches
+}
+
+func repeatMatches(rs []rune, charBits float64) []match {
+	var matches []match
+	for unit := 1; unit <= len(rs)/2; unit++ {
+		for i := 0; i+unit*2 <= len(rs); {
+			j := i + unit
+			for j+unit <= len(rs) && slices.Equal(rs[j:j+unit], rs[i:i+unit]) {
+				j += unit
+			}
+			count := (j - i) / unit
+			if count >= 2 && j-i >= 3 {
+				matches = append(matches, match{
+					i: i, j: j,
+					kind:    _kindRepeat,
+					token:   string(rs[i:j]),
+					entropy: float64(unit)*charBits + math.Log2(float64(count)),
+				})
+				i = j
+			} else {
+				i++
+			}
+		}
+	}
+	return matches
+}
+
+func sequenceMatches(rs []rune) []match {
+	var matches []match
+	for i := 0; i < len(rs)-2; {
+		delta := rs[i+1] - rs[i]
+		j := i + 1
+		if delta == 1 || delta == -1 {
+			for j < len(rs) && rs[j]-rs[j-1] == delta && sameClass(rs[j], rs[i]) {
+				j++
+			}
+		}
+		if j-i >= 3 {
+			bits := math.Log2(26)
+			if unicode.IsDigit(rs[i]) {
+				bits = math.Log2(10)
+			}
+			if delta < 0 {
+				bits++
+			}
+			matches = append(matches, match{
+				i: i, j: j,
+				kind:    _kindSequence,
+				token:   string(rs[i:j]),
+				entropy: bits + math.Log2(float64(j-i)),
+			})
+			i = j
+		} else {
+			i++
+		}
+	}
+	return matches
+}
+
+func sameClass(a, b rune) bool {
+	switch {
+	case unicode.IsDigit(a):
+		return unicode.IsDigit(b)
+	case unicode.IsLower(a):
+		return unicode.IsLower(b)
+	case unicode.IsUpper(a):
+		return unicode.IsUpper(b)
+	}
+	return false
+}
+
+// dateMatches finds years, MMDD, YYMMDD and YYYYMMDD like digits, with or
+// without separators.
+func dateMatches(password string) []match {
+	var matches []match
+	runeIndex := func(byteIndex int) int {
+		return utf8.RuneCountInString(password[:byteIndex])
+	}
+
+	for _, loc := range dateRegexp.FindAllStringIndex(password, -1) {
+		token := password[loc[0]:loc[1]]
+		parts := strings.FieldsFunc(token, func(r rune) bool { return !unicode.IsDigit(r) })
+		if len(parts) == 3 && isDate(parts[0], parts[1], parts[2]) {
+			matches = append(matches, match{
+				i: runeIndex(loc[0]), j: runeIndex(loc[1]),
+				kind:    _kindDate,
+				token:   token,
+				entropy: math.Log2(365*200) + 2,
+			})
+		}
+	}
+
+	rs := []rune(password)
+	for i := range rs {
+		for _, n := range []int{4, 6, 8} {
+			if i+n > len(rs) || !allDigits(rs[i:i+n]) {
+				continue
+			}
+			d := string(rs[i : i+n])
+			var entropy float64
+			switch n {
+			case 4:
+				if isYear(d) {
+					entropy = math.Log2(200)
+				} else if isDate("2000", d[:2], d[2:]) || isDate("2000", d[2:], d[:2]) {
+					entropy = math.Log2(366)
+				}
+			case 6:
+				if isDate("20"+d[:2], d[2:4], d[4:]) || isDate("20"+d[4:], d[:2], d[2:4]) || isDate("20"+d[4:], d[2:4], d[:2]) {
+					entropy = math.Log2(365 * 100)
+				}
+			case 8:
+				if isDate(d[:4], d[4:6], d[6:]) || isDate(d[4:], d[:2], d[2:4]) || isDate(d[4:], d[2:4], d[:2]) {
+					entropy = math.Log2(365 * 200)
+				}
+			}
+			if entropy > 0 {
+				matches = append(matches, match{i: i, j: i + n, kind: _kindDate, token: d, entropy: entropy})
+			}
+		}
+	}
+	return matches
+}
+
+func allDigits(rs []rune) bool {
+	for _, r := range rs {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func isYear(s string) bool {
+	return len(s) == 4 && (strings.HasPrefix(s, "19") || strings.HasPrefix(s, "20"))
+}
+
+func isDate(year, month, day string) bool {
+	if len(year) == 2 {
+		year = "20" + year
+	}
+	if !isYear(year) {
+		return false
+	}
+	_, err := time.Parse("2006-1-2", fmt.Sprintf("%s-%s-%s", year, strings.TrimLeft(month, "0"), strings.TrimLeft(day, "0")))
+	return err == nil
+}
+
+// crackScenarios are the attack scenarios used to estimate the crack time.
+var crackScenarios = []struct {
+	name             string
+	guessesPerSecond float64
+}{
+	{"在线攻击 (限速, 100 次/小时)", 100.0 / 3600},
+	{"在线攻击 (不限速, 10 次/秒)", 10},
+	{"离线攻击 (慢哈希, 1e4 次/秒)", 1e4},
+	{"离线攻击 (快哈希, 1e10 次/秒)", 1e10},
+}
+
+func formatSeconds(sec float64) string {
+	units := []struct {
+		name string
+		sec  float64
+	}{
+		{"年", 365 * 24 * 3600},
+		{"个月", 30 * 24 * 3600},
+		{"天", 24 * 3600},
+		{"小时", 3600},
+		{"分钟", 60},
+		{"秒", 1},
+	}
+	if sec < 1 {
+		return "不到 1 秒"
+	}
+	if sec > 100*units[0].sec {
+		return "超过 100 年"
+	}
+	for _, u := range units {
+		if sec >= u.sec {
+			return fmt.Sprintf("%.0f %s", math.Round(sec/u.sec), u.name)
+		}
+	}
+	return "不到 1 秒"
+}
+
+// printStrength prints the summary line and warnings of a password.
+func printStrength(w io.Writer, password string) {
+	s := analyzePassword(password)
+	fmt.Fprintf(w, "强度: %d/4 %s, 熵: %.1f bits\n", s.Score, s.ScoreName(), s.Entropy)
+	for _, warning := range s.Warnings {
+		fmt.Fprintf(w, "  - %s\n", warning)
+	}
+}
+
+func (m *PwdGenCLI) Check(cmd *cobra.Command, args []string) error {
+	fromClipboard, _ := cmd.Flags().GetBool("clipboard")
+
+	password, err := readSecret("密码: ", fromClipboard)
+	if err != nil {
+		return err
+	}
+	if password == "" {
+		return fmt.Errorf("password is empty")
+	}
+
+	s := analyzePassword(password)
+	fmt.Printf("强度: %d/4 %s\n", s.Score, s.ScoreName())
+	fmt.Printf("熵: %.1f bits\n", s.Entropy)
+	fmt.Println("破解时间:")
+	for _, c := range crackScenarios {
+		fmt.Printf("  %s: %s\n", c.name, formatSeconds(s.CrackSeconds(c.guessesPerSecond)))
+	}
+	if len(s.Warnings) > 0 {
+		fmt.Println("警告:")
+		for _, warning := range s.Warnings {
+			fmt.Printf("  - %s\n", warning)
+		}
+	}
+	return nil
+}
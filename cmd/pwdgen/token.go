@@ -0,0 +1,203 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"math/big"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/jaevor/go-nanoid"
+	"github.com/oklog/ulid/v2"
+	"github.com/rs/xid"
+	"github.com/spf13/cobra"
+	"github.com/yitter/idgenerator-go/idgen"
+)
+
+const (
+	base58Alphabet = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+	base62Alphabet = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+	// _checksumLength is the length of the base62 CRC32 checksum, the same
+	// as the GitHub tokens.
+	_checksumLength = 6
+	// _maxWorkerId is the largest worker id with the default 6 bit length
+	// of idgenerator.
+	_maxWorkerId = 1<<6 - 1
+)
+
+// tokenKinds lists every kind supported by token, random tokens first.
+var tokenKinds = []string{"hex", "base32", "base64url", "base58", "uuid", "uuidv7", "ulid", "xid", "nanoid", "snowflake"}
+
+type tokenConf struct {
+	Kind     string
+	Bytes    int
+	Prefix   string
+	Checksum bool
+	Length   int
+	WorkerId uint16
+}
+
+// baseEncode encodes b as a big-endian number in the alphabet, leading zero
+// bytes are kept as leading zero digits like base58 of bitcoin.
+func baseEncode(b []byte, alphabet string) string {
+	var (
+		n    = new(big.Int).SetBytes(b)
+		base = big.NewInt(int64(len(alphabet)))
+		mod  = new(big.Int)
+		out  []byte
+	)
+	for n.Sign() > 0 {
+		n.DivMod(n, base, mod)
+		out = append(out, alphabet[mod.Int64()])
+	}
+	for _, c := range b {
+		if c != 0 {
+			break
+		}
+		out = append(out, alphabet[0])
+	}
+	for i, j := 0, len(out)-1; i < j; i, j = i+1, j-1 {
+		out[i], out[j] = out[j], out[i]
+	}
+	return string(out)
+}
+
+// tokenChecksum returns the CRC32 of s in base62, padded to _checksumLength,
+// so a token can be checked offline for typos.
+func tokenChecksum(s string) string {
+	sum := crc32.ChecksumIEEE([]byte(s))
+	c := baseEncode([]byte{byte(sum >> 24), byte(sum >> 16), byte(sum >> 8), byte(sum)}, base62Alphabet)
+	return strings.Repeat("0", _checksumLength-len(c)) + c
+}
+
+// newTokenGenerator returns a function that generates one token per call.
+func newTokenGenerator(reader io.Reader, conf tokenConf) (func() (string, error), error) {
+	random := func(encode func([]byte) string) (func() (string, error), error) {
+		if conf.Bytes < 8 || conf.Bytes > 1024 {
+			return nil, errors.New("bytes must range 8-1024")
+		}
+		return func() (string, error) {
+			b := make([]byte, conf.Bytes)
+			if _, err := io.ReadFull(reader, b); err != nil {
+				return "", err
+			}
+			return encode(b), nil
+		}, nil
+	}
+
+	switch conf.Kind {
+	case "hex":
+		return random(hex.EncodeToString)
+	case "base32":
+		return random(base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString)
+	case "base64url":
+		return random(base64.RawURLEncoding.EncodeToString)
+	case "base58":
+		return random(func(b []byte) string { return baseEncode(b, base58Alphabet) })
+	case "uuid":
+		return func() (string, error) {
+			id, err := uuid.NewRandomFromReader(reader)
+			return id.String(), err
+		}, nil
+	case "uuidv7":
+		return func() (string, error) {
+			id, err := uuid.NewV7FromReader(reader)
+			return id.String(), err
+		}, nil
+	case "ulid":
+		entropy := ulid.Monotonic(reader, 0)
+		return func() (string, error) {
+			id, err := ulid.New(ulid.Now(), entropy)
+			return id.String(), err
+		}, nil
+	case "xid":
+		return func() (string, error) {
+			return xid.New().String(), nil
+		}, nil
+	case "nanoid":
+		gen, err := nanoid.Standard(conf.Length)
+		if err != nil {
+			return nil, fmt.Errorf("nanoid err: %w", err)
+		}
+		return func() (string, error) {
+			return gen(), nil
+		}, nil
+	case "snowflake":
+		if conf.WorkerId > _maxWorkerId {
+			return nil, fmt.Errorf("worker id must range 0-%d", _maxWorkerId)
+		}
+		idgen.SetIdGenerator(idgen.NewIdGeneratorOptions(conf.WorkerId))
+		return func() (string, error) {
+			return strconv.FormatInt(idgen.NextId(), 10), nil
+		}, nil
+	default:
+		return nil, fmt.Errorf("unsupported kind %s, want one of %s", conf.Kind, strings.Join(tokenKinds, ", "))
+	}
+}
+
+// newToken wraps the generator with the prefix and checksum of conf.
+func newToken(gen func() (string, error), conf tokenConf) (string, error) {
+	s, err := gen()
+	if err != nil {
+		return "", err
+	}
+	s = conf.Prefix + s
+	if conf.Checksum {
+		s += tokenChecksum(s)
+	}
+	return s, nil
+}
+
+func (m *PwdGenCLI) Token(cmd *cobra.Command, args []string) error {
+	count, _ := cmd.Flags().GetInt("count")
+
+	conf := tokenConf{Kind: "hex"}
+	if len(args) > 0 {
+		conf.Kind = strings.ToLower(args[0])
+	}
+	conf.Bytes, _ = cmd.Flags().GetInt("bytes")
+	conf.Prefix, _ = cmd.Flags().GetString("prefix")
+	conf.Checksum, _ = cmd.Flags().GetBool("checksum")
+	conf.Length, _ = cmd.Flags().GetInt("length")
+	conf.WorkerId, _ = cmd.Flags().GetUint16("worker-id")
+
+	if count < 1 || count > _maxCount {
+		return fmt.Errorf("count must range 1-%d", _maxCount)
+	}
+	gen, err := newTokenGenerator(rand.Reader, conf)
+	if err != nil {
+		return err
+	}
+
+	if count == 1 {
+		s, err := newToken(gen, conf)
+		if err != nil {
+			return err
+		}
+		if err := writeOutput(cmd, s); err != nil {
+			return err
+		}
+		switch conf.Kind {
+		case "hex", "base32", "base64url", "base58":
+			fmt.Fprintf(os.Stderr, "熵: %d bits\n", conf.Bytes*8)
+		}
+		return nil
+	}
+	for i := 0; i < count; i++ {
+		s, err := newToken(gen, conf)
+		if err != nil {
+			return err
+		}
+		fmt.Println(s)
+	}
+	return nil
+}